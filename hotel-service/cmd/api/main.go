@@ -1,18 +1,48 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/olivere/elastic/v7"
 	"github.com/tfgoztok/hotel-service/internal/api"
 	"github.com/tfgoztok/hotel-service/internal/config"
 	"github.com/tfgoztok/hotel-service/internal/db"
+	"github.com/tfgoztok/hotel-service/internal/health"
 	"github.com/tfgoztok/hotel-service/internal/messaging"
+	"github.com/tfgoztok/hotel-service/internal/metrics"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+	"github.com/tfgoztok/hotel-service/internal/search"
+	"github.com/tfgoztok/hotel-service/internal/service"
+	"github.com/tfgoztok/hotel-service/internal/tracing"
 	"github.com/tfgoztok/hotel-service/pkg/logger"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// migrationPath is the directory holding the database migrations.
+const migrationPath = "./internal/db/migrations"
+
+// Exit codes. Startup failures exit with 1 through logger.Fatal.
+const (
+	exitOK                 = 0 // Shut down cleanly after SIGINT or SIGTERM
+	exitServerFailed       = 1 // The HTTP server stopped by itself, e.g. because the port is taken
+	exitShutdownIncomplete = 2 // Requests or outbox messages were abandoned at the shutdown deadline
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run starts the service and blocks until it has shut down, returning the exit code.
+func run() int {
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
@@ -20,36 +50,250 @@ func main() {
 
 	logger := logger.New()
 
+	// Track the dependencies; the service runs degraded while an optional one is down
+	registry := health.NewRegistry(logger,
+		health.WithCheckTimeout(cfg.ReadinessCheckTimeout),
+		health.WithCacheTTL(cfg.ReadinessCacheTTL),
+	)
+
+	// Export traces to the collector if one is configured; otherwise the trace context of
+	// incoming requests is only passed on
+	var tracerProvider *sdktrace.TracerProvider
+	if cfg.OTLPEndpoint != "" {
+		exporter, err := tracing.NewOTLPExporter(context.Background(), cfg.OTLPEndpoint)
+		if err != nil {
+			logger.Fatal("Failed to create trace exporter", "error", err)
+		}
+		tracerProvider = tracing.NewProvider(exporter, cfg.ServiceName, cfg.TraceSampleRatio)
+		tracing.Install(tracerProvider)
+		logger.Info("Exporting traces", "endpoint", cfg.OTLPEndpoint, "sample_ratio", cfg.TraceSampleRatio)
+	} else {
+		tracing.Install(noop.NewTracerProvider())
+	}
+
+	// Collect the metrics exposed on /metrics
+	m := metrics.New()
+
+	// Background workers run until ctx is cancelled at shutdown
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	startWorker := func(work func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			work(ctx)
+		}()
+	}
+
 	database, err := db.Connect(cfg.DatabaseURL)
 	if err != nil {
 		logger.Fatal("Failed to connect to database", "error", err)
 	}
-	defer database.Close()
+	if err := m.RegisterDB(database, "hoteldb"); err != nil {
+		logger.Fatal("Failed to register database metrics", "error", err)
+	}
+	inventory := metrics.NewInventoryCollector(repository.NewHotelRepository(database), repository.NewContactRepository(database), logger)
+	if err := m.Register(inventory); err != nil {
+		logger.Fatal("Failed to register inventory metrics", "error", err)
+	}
+	postgresDep := registry.Register(health.Postgres, true, database.PingContext)
+	startWorker(func(ctx context.Context) {
+		health.Monitor(ctx, postgresDep, cfg.DependencyCheckInterval, database.PingContext)
+	})
 
-	rabbitMQ, err := messaging.NewRabbitMQ(cfg.RabbitMQURL)
+	// The service is ready once the schema is at or past the newest migration it ships with
+	latestMigration, err := db.LatestMigration(migrationPath)
+	if err != nil {
+		logger.Fatal("Failed to read migrations", "error", err)
+	}
+	migrationsDep := registry.Register(health.Migrations, true, func(ctx context.Context) error {
+		return db.CheckMigrationVersion(ctx, database, latestMigration)
+	})
+
+	// The readiness of RabbitMQ is the state of its connection
+	rabbitMQDep := registry.Register(health.RabbitMQ, cfg.RabbitMQRequired, nil)
+
+	// Connect to RabbitMQ; an optional broker is connected to in the background and report
+	// requests wait in the outbox meanwhile
+	rabbitMQOptions := []messaging.Option{messaging.WithLogger(logger), messaging.WithConnectionListener(rabbitMQDep.Set), messaging.WithPublishListener(m.ObservePublish)}
+	if !cfg.RabbitMQRequired {
+		rabbitMQOptions = append(rabbitMQOptions, messaging.WithConnectInBackground())
+	}
+	if cfg.SearchBackend == config.SearchBackendElasticsearch {
+		// The search index is kept in sync from the hotel and contact events
+		rabbitMQOptions = append(rabbitMQOptions, messaging.WithTopology(messaging.DefaultTopology().WithSearchIndex()))
+	}
+	rabbitMQ, err := messaging.NewRabbitMQ(cfg.RabbitMQURL, rabbitMQOptions...)
 	if err != nil {
 		logger.Fatal("Failed to connect to RabbitMQ", "error", err)
 	}
-	defer rabbitMQ.Close()
 
-	// Connect to Elasticsearch
+	// Create the Elasticsearch client without contacting the cluster; its reachability is
+	// monitored below, and a required cluster must be reachable now
 	esClient, err := elastic.NewClient(
 		elastic.SetURL(cfg.ElasticsearchURL),
 		elastic.SetSniff(false),
+		elastic.SetHealthcheck(false),
 	)
 	if err != nil {
-		logger.Fatal("Failed to connect to Elasticsearch", "error", err)
+		logger.Fatal("Failed to create Elasticsearch client", "error", err)
+	}
+	pingElasticsearch := func(ctx context.Context) error {
+		_, _, err := esClient.Ping(cfg.ElasticsearchURL).Do(ctx)
+		return err
+	}
+	elasticsearchDep := registry.Register(health.Elasticsearch, cfg.ElasticsearchRequired, func(ctx context.Context) error {
+		res, err := esClient.ClusterHealth().Do(ctx)
+		if err != nil {
+			return err
+		}
+		if res.Status == "red" {
+			return fmt.Errorf("cluster health is %s", res.Status)
+		}
+		return nil
+	})
+	if cfg.ElasticsearchRequired {
+		pingCtx, cancelPing := context.WithTimeout(ctx, cfg.ReadinessCheckTimeout)
+		err := pingElasticsearch(pingCtx)
+		cancelPing()
+		if err != nil {
+			logger.Fatal("Failed to connect to Elasticsearch", "error", err)
+		}
 	}
 
 	// Run migrations
-	if err := db.RunMigrations(database, "./internal/db/migrations"); err != nil {
+	if err := db.RunMigrations(database, migrationPath); err != nil {
 		logger.Fatal("Failed to run migrations", "error", err)
 	}
+	migrationsDep.Set(nil)
+
+	// Publish queued outbox messages in the background
+	dispatcher := messaging.NewDispatcher(repository.NewOutboxRepository(database), rabbitMQ, logger)
+	startWorker(dispatcher.Run)
+
+	// Reflect the status updates published by the report service
+	reportService := service.NewReportService(repository.NewReportRepository(database), repository.NewHotelRepository(database))
+	if err := rabbitMQ.Consume(messaging.ReportStatusQueue, messaging.NewReportStatusHandler(reportService, logger)); err != nil {
+		logger.Fatal("Failed to consume report status updates", "error", err)
+	}
+
+	var (
+		searcher service.HotelSearcher
+		indexer  *search.Indexer
+	)
+	checkElasticsearch := pingElasticsearch
+	switch cfg.SearchBackend {
+	case config.SearchBackendElasticsearch:
+		// Apply the hotel and contact events the dispatcher publishes to the search index outside
+		// of the requests, holding them back while the index is down
+		searchIndex := search.NewIndex(esClient)
+		indexer = search.NewIndexer(searchIndex, repository.NewHotelRepository(database), repository.NewOfficialRepository(database), elasticsearchDep.Up)
+		if err := rabbitMQ.Consume(messaging.SearchIndexQueue, messaging.NewEventHandler(indexer, logger)); err != nil {
+			logger.Fatal("Failed to consume hotel events", "error", err)
+		}
+		searcher = searchIndex
+
+		// Once the cluster is reachable, create the index if needed and catch up on the
+		// changes made while it was not
+		checkElasticsearch = func(ctx context.Context) error {
+			if err := pingElasticsearch(ctx); err != nil {
+				return err
+			}
+			if !elasticsearchDep.Up() {
+				if err := searchIndex.Ensure(ctx); err != nil {
+					return err
+				}
+			}
+			if err := indexer.Flush(ctx); err != nil {
+				logger.Error("Failed to apply pending search index updates", "pending", indexer.Pending(), "error", err)
+			}
+			return nil
+		}
+	case config.SearchBackendPostgres:
+		searcher = repository.NewHotelRepository(database)
+	}
+	logger.Info("Using hotel search backend", "backend", cfg.SearchBackend)
+	startWorker(func(ctx context.Context) {
+		health.Monitor(ctx, elasticsearchDep, cfg.DependencyCheckInterval, checkElasticsearch)
+	})
 
-	router := api.NewRouter(database, logger, rabbitMQ, esClient)
+	router := api.NewRouter(database, logger, esClient, searcher, registry, m)
+	server := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      router,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
+	}
 
 	logger.Info("Starting server", "port", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, router); err != nil {
-		logger.Fatal("Server failed to start", "error", err)
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe() }()
+
+	// Wait for a shutdown signal; a second signal kills the process right away
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+	code := exitOK
+	select {
+	case err := <-serverErr:
+		logger.Error("Server failed", "error", err)
+		registry.Drain()
+		code = exitServerFailed
+	case <-signals.Done():
+		stopSignals()
+		logger.Info("Shutdown signal received, draining requests", "delay", cfg.ShutdownDelay, "timeout", cfg.ShutdownTimeout)
+
+		// Fail readiness first so load balancers stop routing here before the listener closes
+		registry.Drain()
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
+
+	// Stop accepting connections and wait for the in-flight requests
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to drain requests before the deadline", "error", err)
+		server.Close()
+		code = maxExitCode(code, exitShutdownIncomplete)
+	}
+
+	// Stop the background workers, then publish what the last requests queued
+	stopWorkers()
+	workers.Wait()
+	if err := dispatcher.Flush(shutdownCtx); err != nil {
+		logger.Error("Failed to flush the outbox; remaining messages are published after restart", "error", err)
+		code = maxExitCode(code, exitShutdownIncomplete)
+	}
+	if indexer != nil && indexer.Pending() > 0 {
+		if err := indexer.Flush(shutdownCtx); err != nil {
+			logger.Error("Failed to apply pending search index updates; run a reindex", "pending", indexer.Pending(), "error", err)
+			code = maxExitCode(code, exitShutdownIncomplete)
+		}
+	}
+
+	// Close the connections in reverse order of use: the broker, then the database pool
+	rabbitMQ.Close()
+	if err := database.Close(); err != nil {
+		logger.Error("Failed to close the database", "error", err)
+	}
+
+	// Export the spans still buffered
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Failed to export the remaining spans", "error", err)
+		}
+	}
+
+	logger.Info("Shutdown complete", "exit_code", code)
+	return code
+}
+
+// maxExitCode returns the more severe of two exit codes.
+func maxExitCode(a, b int) int {
+	if a > b {
+		return a
 	}
+	return b
 }
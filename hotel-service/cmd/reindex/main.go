@@ -0,0 +1,89 @@
+// Command reindex rebuilds the hotel search index from the database. Searches keep using the
+// current index until the rebuilt one replaces it.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/olivere/elastic/v7"
+	"github.com/tfgoztok/hotel-service/internal/config"
+	"github.com/tfgoztok/hotel-service/internal/db"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+	"github.com/tfgoztok/hotel-service/internal/search"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+)
+
+// pageSize is the number of hotels read from the database at a time.
+const pageSize = 500
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	logger := logger.New()
+
+	database, err := db.Connect(cfg.DatabaseURL)
+	if err != nil {
+		logger.Fatal("Failed to connect to database", "error", err)
+	}
+	defer database.Close()
+
+	esClient, err := elastic.NewClient(
+		elastic.SetURL(cfg.ElasticsearchURL),
+		elastic.SetSniff(false),
+	)
+	if err != nil {
+		logger.Fatal("Failed to connect to Elasticsearch", "error", err)
+	}
+
+	hotelRepo := repository.NewHotelRepository(database)
+	contactRepo := repository.NewContactRepository(database)
+	officialRepo := repository.NewOfficialRepository(database)
+
+	// Read the hotels in keyset pages, with the contacts and officials of each page in one query each
+	load := func(yield func(*search.Document) error) error {
+		ctx := context.Background()
+		filter := models.HotelFilter{Limit: pageSize}
+		for {
+			hotels, err := hotelRepo.List(ctx, filter)
+			if err != nil {
+				return err
+			}
+			if len(hotels) == 0 {
+				return nil
+			}
+
+			ids := make([]uuid.UUID, len(hotels))
+			for i, hotel := range hotels {
+				ids[i] = hotel.ID
+			}
+			contacts, err := contactRepo.GetByHotelIDs(ctx, ids)
+			if err != nil {
+				return err
+			}
+			officials, err := officialRepo.GetByHotelIDs(ctx, ids)
+			if err != nil {
+				return err
+			}
+			for _, hotel := range hotels {
+				if err := yield(search.NewDocument(hotel, contacts[hotel.ID], officials[hotel.ID])); err != nil {
+					return err
+				}
+			}
+
+			last := hotels[len(hotels)-1]
+			filter.After = &models.HotelCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
+
+	count, err := search.NewIndex(esClient).Rebuild(context.Background(), load)
+	if err != nil {
+		logger.Fatal("Failed to rebuild hotel search index", "error", err)
+	}
+	logger.Info("Rebuilt hotel search index", "hotels", count)
+}
@@ -0,0 +1,118 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/tfgoztok/hotel-service/internal/metrics"
+)
+
+const (
+	noRootField    = "none"     // Labels operations that resolved no root field
+	manyRootFields = "multiple" // Labels operations that resolved several root fields
+)
+
+// operationKey is the context key of the operation being executed.
+type operationKey struct{}
+
+// operation collects the root fields resolved by the operation being executed. Operations are
+// labeled by their root field from the schema rather than by the client-supplied operation
+// name, so the number of series is bounded by the schema.
+type operation struct {
+	mu       sync.Mutex
+	field    string // Root field, named Type.field
+	multiple bool   // Set once a second root field is resolved
+}
+
+// label returns the metric label of the operation.
+func (op *operation) label() string {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	switch {
+	case op.multiple:
+		return manyRootFields
+	case op.field == "":
+		return noRootField
+	default:
+		return op.field
+	}
+}
+
+// MetricsExtension is a graphql.Extension recording the duration of executed operations and of
+// the resolvers of their root fields. Nested fields are resolved from the data the root
+// resolvers loaded, so they are not timed.
+type MetricsExtension struct {
+	metrics *metrics.Metrics
+}
+
+// NewMetricsExtension creates a MetricsExtension recording to m.
+func NewMetricsExtension(m *metrics.Metrics) *MetricsExtension {
+	return &MetricsExtension{metrics: m}
+}
+
+// Init implements graphql.Extension.
+func (e *MetricsExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return context.WithValue(ctx, operationKey{}, &operation{})
+}
+
+// Name implements graphql.Extension.
+func (e *MetricsExtension) Name() string {
+	return "metrics"
+}
+
+// ParseDidStart implements graphql.Extension.
+func (e *MetricsExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+// ValidationDidStart implements graphql.Extension.
+func (e *MetricsExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+// ExecutionDidStart implements graphql.Extension, timing the operation.
+func (e *MetricsExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	start := time.Now()
+	return ctx, func(result *graphql.Result) {
+		label := noRootField
+		if op, ok := ctx.Value(operationKey{}).(*operation); ok {
+			label = op.label()
+		}
+		e.metrics.ObserveGraphQLOperation(label, time.Since(start), result.HasErrors())
+	}
+}
+
+// ResolveFieldDidStart implements graphql.Extension, timing the resolvers of root fields.
+func (e *MetricsExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	if info.ParentType != info.Schema.QueryType() && info.ParentType != info.Schema.MutationType() {
+		return ctx, func(interface{}, error) {}
+	}
+	field := info.ParentType.Name() + "." + info.FieldName
+	if op, ok := ctx.Value(operationKey{}).(*operation); ok {
+		op.mu.Lock()
+		if op.field == "" {
+			op.field = field
+		} else if op.field != field {
+			op.multiple = true // Aliases of the same field still count as one
+		}
+		op.mu.Unlock()
+	}
+
+	start := time.Now()
+	return ctx, func(_ interface{}, err error) {
+		e.metrics.ObserveGraphQLResolver(field, time.Since(start), err)
+	}
+}
+
+// HasResult implements graphql.Extension.
+func (e *MetricsExtension) HasResult() bool {
+	return false
+}
+
+// GetResult implements graphql.Extension.
+func (e *MetricsExtension) GetResult(context.Context) interface{} {
+	return nil
+}
@@ -1,42 +1,94 @@
 package graphql
 
 import (
+	"strings"
+
 	"github.com/graphql-go/graphql"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/models"
 	"github.com/tfgoztok/hotel-service/internal/service"
 )
 
-// GraphQLService is a struct that holds the hotel service.
+// GraphQLService is a struct that holds the hotel and report services.
 type GraphQLService struct {
-	hotelService *service.HotelService // Reference to the hotel service for data retrieval.
+	hotelService  *service.HotelService  // Reference to the hotel service for data retrieval.
+	reportService *service.ReportService // Reference to the report service for statistics.
 }
 
-// NewGraphQLService initializes a new GraphQLService with the provided hotel service.
-func NewGraphQLService(hotelService *service.HotelService) *GraphQLService {
-	return &GraphQLService{hotelService: hotelService} // Return a new instance of GraphQLService.
+// NewGraphQLService initializes a new GraphQLService with the provided services.
+func NewGraphQLService(hotelService *service.HotelService, reportService *service.ReportService) *GraphQLService {
+	return &GraphQLService{hotelService: hotelService, reportService: reportService} // Return a new instance of GraphQLService.
 }
 
 // Schema defines the GraphQL schema for the service, including types and queries.
 func (s *GraphQLService) Schema() (graphql.Schema, error) {
 	// Define the Hotel type with its fields.
+	hotelFields := graphql.Fields{
+		"id":              &graphql.Field{Type: graphql.String}, // Field for hotel ID.
+		"officialName":    &graphql.Field{Type: graphql.String}, // Field for official name.
+		"officialSurname": &graphql.Field{Type: graphql.String}, // Field for official surname.
+		"companyTitle":    &graphql.Field{Type: graphql.String}, // Field for company title.
+		"location":        &graphql.Field{Type: graphql.String}, // Field for location.
+		"latitude":        &graphql.Field{Type: graphql.Float},  // Field for latitude.
+		"longitude":       &graphql.Field{Type: graphql.Float},  // Field for longitude.
+	}
 	hotelType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Hotel", // Name of the GraphQL type.
-		Fields: graphql.Fields{
-			"id":              &graphql.Field{Type: graphql.String}, // Field for hotel ID.
-			"officialName":    &graphql.Field{Type: graphql.String}, // Field for official name.
-			"officialSurname": &graphql.Field{Type: graphql.String}, // Field for official surname.
-			"companyTitle":    &graphql.Field{Type: graphql.String}, // Field for company title.
-			"location":        &graphql.Field{Type: graphql.String}, // Field for location.
-		},
+		Name:   "Hotel", // Name of the GraphQL type.
+		Fields: hotelFields,
+	})
+
+	// Define the NearbyHotel type: the hotel fields plus the distance from the search center.
+	nearbyHotelFields := graphql.Fields{
+		"distanceKm": &graphql.Field{Type: graphql.Float}, // Field for the distance in kilometers.
+	}
+	for name, field := range hotelFields {
+		nearbyHotelFields[name] = &graphql.Field{Type: field.Type, Resolve: resolveNearbyHotelField}
+	}
+	nearbyHotelType := graphql.NewObject(graphql.ObjectConfig{
+		Name:   "NearbyHotel", // Name of the GraphQL type.
+		Fields: nearbyHotelFields,
+	})
+
+	// Define the ContactType enum from the canonical set in the models package.
+	contactTypeValues := graphql.EnumValueConfigMap{}
+	for _, t := range models.ContactTypes {
+		contactTypeValues[string(t)] = &graphql.EnumValueConfig{Value: t}
+	}
+	contactTypeEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:   "ContactType", // Name of the GraphQL enum.
+		Values: contactTypeValues,
 	})
 
 	// Define the Contact type with its fields.
 	contactType := graphql.NewObject(graphql.ObjectConfig{
 		Name: "Contact", // Name of the GraphQL type.
 		Fields: graphql.Fields{
-			"id":      &graphql.Field{Type: graphql.String}, // Field for contact ID.
-			"HotelID": &graphql.Field{Type: graphql.String}, // Field for associated hotel ID.
-			"Type":    &graphql.Field{Type: graphql.String}, // Field for contact type.
-			"Content": &graphql.Field{Type: graphql.String}, // Field for contact content.
+			"id":      &graphql.Field{Type: graphql.String},  // Field for contact ID.
+			"HotelID": &graphql.Field{Type: graphql.String},  // Field for associated hotel ID.
+			"Type":    &graphql.Field{Type: contactTypeEnum}, // Field for contact type.
+			"Content": &graphql.Field{Type: graphql.String},  // Field for contact content.
+		},
+	})
+
+	// Define the LocationStats type with its fields.
+	locationStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LocationStats", // Name of the GraphQL type.
+		Fields: graphql.Fields{
+			"location":   &graphql.Field{Type: graphql.String}, // Field for the location.
+			"hotelCount": &graphql.Field{Type: graphql.Int},    // Field for the number of hotels.
+			"phoneCount": &graphql.Field{Type: graphql.Int},    // Field for the number of phone contacts.
+		},
+	})
+
+	// Define the LocationStatistics type with its fields.
+	locationStatisticsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LocationStatistics", // Name of the GraphQL type.
+		Fields: graphql.Fields{
+			"location":      &graphql.Field{Type: graphql.String},   // Field for the location.
+			"hotelCount":    &graphql.Field{Type: graphql.Int},      // Field for the number of hotels.
+			"phoneCount":    &graphql.Field{Type: graphql.Int},      // Field for the number of phone contacts.
+			"emailCount":    &graphql.Field{Type: graphql.Int},      // Field for the number of email contacts.
+			"lastUpdatedAt": &graphql.Field{Type: graphql.DateTime}, // Field for the latest change in the location.
 		},
 	})
 
@@ -49,14 +101,38 @@ func (s *GraphQLService) Schema() (graphql.Schema, error) {
 				Args: graphql.FieldConfigArgument{
 					"location": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}, // Required location argument.
 				},
-				Resolve: s.resolveHotelsByLocation, // Resolver function for this query.
+				Resolve: traceResolver("Query.hotelsByLocation", s.resolveHotelsByLocation), // Resolver function for this query, traced.
 			},
 			"contactsByLocation": &graphql.Field{
 				Type: graphql.NewList(contactType), // Return a list of contacts.
 				Args: graphql.FieldConfigArgument{
 					"location": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}, // Required location argument.
 				},
-				Resolve: s.resolveContactsByLocation, // Resolver function for this query.
+				Resolve: traceResolver("Query.contactsByLocation", s.resolveContactsByLocation), // Resolver function for this query, traced.
+			},
+			"hotelsNear": &graphql.Field{
+				Type: graphql.NewList(nearbyHotelType), // Return the hotels near a point, closest first.
+				Args: graphql.FieldConfigArgument{
+					"lat":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)}, // Required latitude of the center.
+					"lon":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)}, // Required longitude of the center.
+					"radiusKm": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Float)}, // Required search radius.
+					"limit":    &graphql.ArgumentConfig{Type: graphql.Int},                       // Optional maximum number of hotels.
+				},
+				Resolve: traceResolver("Query.hotelsNear", s.resolveHotelsNear), // Resolver function for this query, traced.
+			},
+			"locationStats": &graphql.Field{
+				Type: locationStatsType, // Return the statistics of a location.
+				Args: graphql.FieldConfigArgument{
+					"location": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}, // Required location argument.
+				},
+				Resolve: traceResolver("Query.locationStats", s.resolveLocationStats), // Resolver function for this query, traced.
+			},
+			"locationStatistics": &graphql.Field{
+				Type: graphql.NewList(locationStatisticsType), // Return the statistics of every location.
+				Args: graphql.FieldConfigArgument{
+					"sort": &graphql.ArgumentConfig{Type: graphql.String}, // Optional sort field, prefixed with "-" for descending order.
+				},
+				Resolve: traceResolver("Query.locationStatistics", s.resolveLocationStatistics), // Resolver function for this query, traced.
 			},
 		},
 	})
@@ -81,3 +157,40 @@ func (s *GraphQLService) resolveContactsByLocation(p graphql.ResolveParams) (int
 	}
 	return s.hotelService.GetContactsByLocation(p.Context, location) // Call the hotel service to get contact by location.
 }
+
+// resolveLocationStats is the resolver function for the locationStats query.
+func (s *GraphQLService) resolveLocationStats(p graphql.ResolveParams) (interface{}, error) {
+	location, ok := p.Args["location"].(string) // Extract the location argument.
+	if !ok {
+		return nil, nil // Return nil if the location is not valid.
+	}
+	return s.reportService.LocationStats(p.Context, location) // Call the report service to compute the statistics.
+}
+
+// resolveLocationStatistics is the resolver function for the locationStatistics query.
+func (s *GraphQLService) resolveLocationStatistics(p graphql.ResolveParams) (interface{}, error) {
+	value, _ := p.Args["sort"].(string) // Extract the optional sort argument.
+	sort, ok := models.ParseLocationStatisticsSort(value)
+	if !ok {
+		return nil, domain.BadRequest("invalid sort, expected one of " + strings.Join(models.LocationStatisticsSortFields, ", ") + ", optionally prefixed with -")
+	}
+	return s.reportService.StatsByLocation(p.Context, sort) // Call the report service to compute the statistics.
+}
+
+// resolveHotelsNear is the resolver function for the hotelsNear query.
+func (s *GraphQLService) resolveHotelsNear(p graphql.ResolveParams) (interface{}, error) {
+	filter := models.NearbyFilter{}
+	filter.Latitude, _ = p.Args["lat"].(float64)          // Extract the latitude argument.
+	filter.Longitude, _ = p.Args["lon"].(float64)         // Extract the longitude argument.
+	filter.RadiusKm, _ = p.Args["radiusKm"].(float64)     // Extract the radius argument.
+	filter.Limit, _ = p.Args["limit"].(int)               // Extract the optional limit argument.
+	return s.hotelService.NearbyHotels(p.Context, filter) // Call the hotel service to search by proximity.
+}
+
+// resolveNearbyHotelField resolves a hotel field of a NearbyHotel from its embedded hotel.
+func resolveNearbyHotelField(p graphql.ResolveParams) (interface{}, error) {
+	if nearby, ok := p.Source.(*models.NearbyHotel); ok {
+		p.Source = nearby.Hotel
+	}
+	return graphql.DefaultResolveFn(p)
+}
@@ -0,0 +1,117 @@
+package graphql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/tfgoztok/hotel-service/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes of GraphQL spans.
+const (
+	operationNameKey = attribute.Key("graphql.operation.name")
+	operationTypeKey = attribute.Key("graphql.operation.type")
+	fieldNameKey     = attribute.Key("graphql.field.name")
+)
+
+// anonymousOperation names the spans of operations without a name.
+const anonymousOperation = "anonymous"
+
+// TracingExtension is a graphql.Extension tracing the execution of operations. The span of an
+// operation is the parent of the spans of its root resolvers, see traceResolver.
+type TracingExtension struct{}
+
+// NewTracingExtension creates a TracingExtension.
+func NewTracingExtension() *TracingExtension {
+	return &TracingExtension{}
+}
+
+// Init implements graphql.Extension.
+func (e *TracingExtension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	return ctx
+}
+
+// Name implements graphql.Extension.
+func (e *TracingExtension) Name() string {
+	return "tracing"
+}
+
+// ParseDidStart implements graphql.Extension.
+func (e *TracingExtension) ParseDidStart(ctx context.Context) (context.Context, graphql.ParseFinishFunc) {
+	return ctx, func(error) {}
+}
+
+// ValidationDidStart implements graphql.Extension.
+func (e *TracingExtension) ValidationDidStart(ctx context.Context) (context.Context, graphql.ValidationFinishFunc) {
+	return ctx, func([]gqlerrors.FormattedError) {}
+}
+
+// ExecutionDidStart implements graphql.Extension, starting the span of the operation. The span
+// is renamed after the operation once its first root field is resolved.
+func (e *TracingExtension) ExecutionDidStart(ctx context.Context) (context.Context, graphql.ExecutionFinishFunc) {
+	ctx, span := tracing.Tracer().Start(ctx, "GraphQL operation")
+	return ctx, func(result *graphql.Result) {
+		if result.HasErrors() {
+			span.SetStatus(codes.Error, result.Errors[0].Message)
+		}
+		span.End()
+	}
+}
+
+// ResolveFieldDidStart implements graphql.Extension, naming the span of the operation after its
+// type and name, e.g. "query IstanbulContacts".
+func (e *TracingExtension) ResolveFieldDidStart(ctx context.Context, info *graphql.ResolveInfo) (context.Context, graphql.ResolveFieldFinishFunc) {
+	if def, ok := info.Operation.(*ast.OperationDefinition); ok && info.ParentType == rootType(info) {
+		span := trace.SpanFromContext(ctx)
+		name := anonymousOperation
+		if def.Name != nil {
+			name = def.Name.Value
+		}
+		span.SetName(def.Operation + " " + name)
+		span.SetAttributes(operationTypeKey.String(def.Operation), operationNameKey.String(name))
+	}
+	return ctx, func(interface{}, error) {}
+}
+
+// HasResult implements graphql.Extension.
+func (e *TracingExtension) HasResult() bool {
+	return false
+}
+
+// GetResult implements graphql.Extension.
+func (e *TracingExtension) GetResult(context.Context) interface{} {
+	return nil
+}
+
+// rootType returns the root type of the operation a field belongs to.
+func rootType(info *graphql.ResolveInfo) graphql.Composite {
+	if def, ok := info.Operation.(*ast.OperationDefinition); ok && def.Operation == ast.OperationTypeMutation {
+		return info.Schema.MutationType()
+	}
+	return info.Schema.QueryType()
+}
+
+// traceResolver wraps the resolver of a root field, e.g. "Query.hotelsByLocation", so that it
+// runs in a span of its own. The SQL statements of the resolver are traced as its children.
+func traceResolver(field string, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		ctx, span := tracing.Tracer().Start(p.Context, field,
+			trace.WithAttributes(fieldNameKey.String(field[strings.LastIndex(field, ".")+1:])),
+		)
+		defer span.End()
+
+		p.Context = ctx
+		result, err := resolve(p)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return result, err
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -22,38 +23,119 @@ func (h *ContactHandler) AddContact(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)                    // Get URL parameters
 	hotelID, err := uuid.Parse(params["id"]) // Parse hotel ID from parameters
 	if err != nil {
-		http.Error(w, "Invalid hotel ID", http.StatusBadRequest) // Handle invalid hotel ID
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID") // Handle invalid hotel ID
 		return
 	}
 
 	var contact models.Contact // Create a new contact instance
 	if err := json.NewDecoder(r.Body).Decode(&contact); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest) // Handle JSON decoding errors
+		writeProblem(w, r, http.StatusBadRequest, err.Error()) // Handle JSON decoding errors
 		return
 	}
 	contact.HotelID = hotelID // Associate the contact with the hotel ID
 
 	if err := h.service.AddContact(r.Context(), &contact); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError) // Handle service errors
+		writeError(w, r, err) // Handle service errors
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)  // Respond with 201 Created
-	json.NewEncoder(w).Encode(contact) // Return the created contact
+	writeJSON(w, http.StatusCreated, contact) // Respond with 201 Created and the created contact
 }
 
-func (h *ContactHandler) DeleteContact(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)                             // Get URL parameters
-	contactID, err := uuid.Parse(params["contactId"]) // Parse contact ID from parameters
+// ListContacts lists the contacts of a hotel, optionally filtered by one or more ?type= values
+func (h *ContactHandler) ListContacts(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)                    // Get URL parameters
+	hotelID, err := uuid.Parse(params["id"]) // Parse hotel ID from parameters
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID") // Handle invalid hotel ID
+		return
+	}
+
+	var types []models.ContactType
+	for _, value := range r.URL.Query()["type"] {
+		contactType, ok := models.ParseContactType(value)
+		if !ok {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid contact type "+value) // Handle unknown contact types
+			return
+		}
+		types = append(types, contactType)
+	}
+
+	contacts, err := h.service.GetContactsByHotelID(r.Context(), hotelID, types...)
+	if err != nil {
+		writeError(w, r, err) // Handle service errors
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contacts)
+}
+
+// GetContact retrieves a single contact of a hotel
+func (h *ContactHandler) GetContact(w http.ResponseWriter, r *http.Request) {
+	hotelID, contactID, ok := parseContactPath(w, r)
+	if !ok {
+		return
+	}
+
+	contact, err := h.service.GetContact(r.Context(), hotelID, contactID)
 	if err != nil {
-		http.Error(w, "Invalid contact ID", http.StatusBadRequest) // Handle invalid contact ID
+		writeError(w, r, err) // Handle service errors
 		return
 	}
 
-	if err := h.service.DeleteContact(r.Context(), contactID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError) // Handle service errors
+	writeJSON(w, http.StatusOK, contact)
+}
+
+// PatchContact partially updates a contact of a hotel using a JSON merge patch (RFC 7386)
+func (h *ContactHandler) PatchContact(w http.ResponseWriter, r *http.Request) {
+	hotelID, contactID, ok := parseContactPath(w, r)
+	if !ok {
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	contact, err := h.service.PatchContact(r.Context(), hotelID, contactID, patch)
+	if err != nil {
+		writeError(w, r, err) // Handle service errors
+		return
+	}
+
+	writeJSON(w, http.StatusOK, contact)
+}
+
+func (h *ContactHandler) DeleteContact(w http.ResponseWriter, r *http.Request) {
+	hotelID, contactID, ok := parseContactPath(w, r)
+	if !ok {
+		return
+	}
+
+	// Only delete the contact if it belongs to the hotel in the path
+	if err := h.service.DeleteContact(r.Context(), hotelID, contactID); err != nil {
+		writeError(w, r, err) // Handle service errors
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent) // Respond with 204 No Content
 }
+
+// parseContactPath parses the hotel and contact IDs of /hotels/{id}/contacts/{contactId},
+// writing a 400 response and returning ok=false if either is invalid
+func parseContactPath(w http.ResponseWriter, r *http.Request) (hotelID, contactID uuid.UUID, ok bool) {
+	params := mux.Vars(r) // Get URL parameters
+	hotelID, err := uuid.Parse(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID") // Handle invalid hotel ID
+		return uuid.Nil, uuid.Nil, false
+	}
+	contactID, err = uuid.Parse(params["contactId"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid contact ID") // Handle invalid contact ID
+		return uuid.Nil, uuid.Nil, false
+	}
+	return hotelID, contactID, true
+}
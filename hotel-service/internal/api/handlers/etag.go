@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+)
+
+// setETag sets the ETag header to the entity version derived from its updated_at timestamp.
+func setETag(w http.ResponseWriter, updatedAt time.Time) {
+	w.Header().Set("ETag", `"`+strconv.FormatInt(updatedAt.UnixMicro(), 10)+`"`)
+}
+
+// ifMatchVersions parses the If-Match header (RFC 9110, section 13.1.1) into the versions the
+// client expects to modify. It returns nil when the header is absent or "*", meaning any version
+// may be replaced, and ok=false when no entity tag in the list can match a version issued by
+// setETag. If-Match uses the strong comparison, so weak tags never match.
+func ifMatchVersions(r *http.Request) (versions []time.Time, ok bool) {
+	header := strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ","))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+
+	for _, tag := range splitETags(header) {
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		micros, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+		if err != nil {
+			continue // A tag of another origin can never match
+		}
+		versions = append(versions, time.UnixMicro(micros).UTC())
+	}
+	return versions, len(versions) > 0
+}
+
+// preconditionError reports a missing resource as a failed precondition if the request was
+// conditional on If-Match: *, which only matches an existing resource.
+func preconditionError(r *http.Request, err error) error {
+	if domain.IsNotFound(err) && strings.TrimSpace(strings.Join(r.Header.Values("If-Match"), ",")) == "*" {
+		return repository.ErrVersionConflict
+	}
+	return err
+}
+
+// splitETags splits a comma-separated list of entity tags. Commas may appear inside the
+// quotes of a tag, so the list is split outside of them only.
+func splitETags(header string) []string {
+	var (
+		tags   []string
+		start  int
+		quoted bool
+	)
+	for i := 0; i < len(header); i++ {
+		switch header[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				tags = append(tags, header[start:i])
+				start = i + 1
+			}
+		}
+	}
+	tags = append(tags, header[start:])
+
+	result := tags[:0]
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag) // Empty list elements are allowed and ignored
+		}
+	}
+	return result
+}
@@ -13,12 +13,14 @@ type GraphQLHandler struct {
     schema graphql.Schema // The GraphQL schema
 }
 
-// NewGraphQLHandler initializes a new GraphQLHandler with the provided GraphQL service
-func NewGraphQLHandler(graphqlService *localGraphQL.GraphQLService) (*GraphQLHandler, error) {
+// NewGraphQLHandler initializes a new GraphQLHandler with the provided GraphQL service,
+// running the given extensions, e.g. metrics, on every request
+func NewGraphQLHandler(graphqlService *localGraphQL.GraphQLService, extensions ...graphql.Extension) (*GraphQLHandler, error) {
     schema, err := graphqlService.Schema() // Retrieve the schema from the service
     if err != nil {
         return nil, err // Return an error if schema retrieval fails
     }
+    schema.AddExtensions(extensions...) // Hook the extensions into parsing, validation and execution
     return &GraphQLHandler{schema: schema}, nil // Return a new GraphQLHandler instance
 }
 
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/tfgoztok/hotel-service/internal/health"
+)
+
+// HealthHandler reports the state of the service and its dependencies
+type HealthHandler struct {
+	registry *health.Registry // Dependencies of the service
+}
+
+// NewHealthHandler creates a new HealthHandler reporting on the given registry
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{registry: registry}
+}
+
+// Health returns the state of every dependency. It responds with 200 while the service works,
+// possibly degraded, and with 503 while a required dependency is down.
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	report := h.registry.Report()
+
+	status := http.StatusOK
+	if report.Status == health.StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// Liveness reports that the process is running and serving HTTP. It checks no dependency, so
+// an orchestrator restarts the service only when it hangs.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// Readiness checks every dependency, reusing a recent result, and reports each check's status
+// and latency. It responds with 200 while the service can serve requests, possibly degraded,
+// and with 503 while a required dependency is down.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	readiness := h.registry.Ready(r.Context())
+
+	status := http.StatusOK
+	if !readiness.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, readiness)
+}
@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
 	"github.com/tfgoztok/hotel-service/internal/service"
 )
 
@@ -25,18 +31,166 @@ func (h *HotelHandler) CreateHotel(w http.ResponseWriter, r *http.Request) {
 	var hotel models.Hotel
 	// Decode the incoming JSON request body into the hotel struct
 	if err := json.NewDecoder(r.Body).Decode(&hotel); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest) // Return error if decoding fails
+		writeProblem(w, r, http.StatusBadRequest, err.Error()) // Return error if decoding fails
 		return
 	}
 
 	// Call the service to create the hotel
 	if err := h.service.CreateHotel(r.Context(), &hotel); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError) // Return error if creation fails
+		writeError(w, r, err) // Return error if creation fails
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated) // Respond with 201 Created
-	json.NewEncoder(w).Encode(hotel)  // Return the created hotel as JSON
+	setETag(w, hotel.UpdatedAt)             // Expose the version for conditional updates
+	writeJSON(w, http.StatusCreated, hotel) // Respond with 201 Created and the created hotel
+}
+
+// UpdateHotel handles the full replacement of a hotel by ID
+func (h *HotelHandler) UpdateHotel(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := uuid.Parse(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID")
+		return
+	}
+
+	versions, ok := ifMatchVersions(r)
+	if !ok {
+		writeError(w, r, repository.ErrVersionConflict)
+		return
+	}
+
+	var hotel models.Hotel
+	if err := json.NewDecoder(r.Body).Decode(&hotel); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	hotel.ID = id // The path identifies the hotel, not the body
+
+	if err := h.service.UpdateHotel(r.Context(), &hotel, versions); err != nil {
+		writeError(w, r, preconditionError(r, err))
+		return
+	}
+
+	setETag(w, hotel.UpdatedAt)
+	writeJSON(w, http.StatusOK, hotel)
+}
+
+// PatchHotel handles partial updates of a hotel by ID using a JSON merge patch (RFC 7386)
+func (h *HotelHandler) PatchHotel(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := uuid.Parse(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID")
+		return
+	}
+
+	versions, ok := ifMatchVersions(r)
+	if !ok {
+		writeError(w, r, repository.ErrVersionConflict)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hotel, err := h.service.PatchHotel(r.Context(), id, patch, versions)
+	if err != nil {
+		writeError(w, r, preconditionError(r, err))
+		return
+	}
+
+	setETag(w, hotel.UpdatedAt)
+	writeJSON(w, http.StatusOK, hotel)
+}
+
+// ListHotels returns a page of hotels filtered by the query string parameters
+func (h *HotelHandler) ListHotels(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.HotelFilter{
+		Location:           query.Get("location"),
+		CompanyTitlePrefix: query.Get("company_title"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	// Parse the optional creation date range
+	for param, target := range map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+	} {
+		if v := query.Get(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, "Invalid "+param+", expected RFC 3339 timestamp")
+				return
+			}
+			*target = &t
+		}
+	}
+
+	switch query.Get("sort") {
+	case "", "created_at":
+	case "-created_at":
+		filter.Descending = true
+	default:
+		writeProblem(w, r, http.StatusBadRequest, "Invalid sort, expected created_at or -created_at")
+		return
+	}
+
+	page, err := h.service.ListHotels(r.Context(), filter, query.Get("cursor"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// NearbyHotels returns the hotels within ?radius_km= kilometers of ?lat= and ?lon=, closest first
+func (h *HotelHandler) NearbyHotels(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter models.NearbyFilter
+
+	for param, target := range map[string]*float64{
+		"lat":       &filter.Latitude,
+		"lon":       &filter.Longitude,
+		"radius_km": &filter.RadiusKm,
+	} {
+		v, err := strconv.ParseFloat(query.Get(param), 64)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "Missing or invalid "+param)
+			return
+		}
+		*target = v
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	hotels, err := h.service.NearbyHotels(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, hotels)
 }
 
 // DeleteHotel handles the deletion of a hotel by ID
@@ -44,13 +198,13 @@ func (h *HotelHandler) DeleteHotel(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)               // Get URL parameters
 	id, err := uuid.Parse(params["id"]) // Parse the hotel ID from parameters
 	if err != nil {
-		http.Error(w, "Invalid hotel ID", http.StatusBadRequest) // Return error if ID is invalid
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID") // Return error if ID is invalid
 		return
 	}
 
 	// Call the service to delete the hotel
 	if err := h.service.DeleteHotel(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError) // Return error if deletion fails
+		writeError(w, r, err) // Return error if deletion fails
 		return
 	}
 
@@ -62,33 +216,45 @@ func (h *HotelHandler) GetHotelDetails(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	id, err := uuid.Parse(params["id"])
 	if err != nil {
-		http.Error(w, "Invalid hotel ID", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID")
 		return
 	}
 
-	hotel, err := h.service.GetHotelDetails(r.Context(), id)
+	include, err := parseIncludes(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	json.NewEncoder(w).Encode(hotel)
-}
-
-// ListOfficials lists the officials of a hotel by ID.
-func (h *HotelHandler) ListOfficials(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, err := uuid.Parse(params["id"])
+	hotel, err := h.service.GetHotelDetails(r.Context(), id, include)
 	if err != nil {
-		http.Error(w, "Invalid hotel ID", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	officials, err := h.service.ListOfficials(r.Context(), id)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	setETag(w, hotel.UpdatedAt)
+	writeJSON(w, http.StatusOK, hotel)
+}
+
+// parseIncludes reads the comma-separated ?include= expansions of hotel details.
+// Without the parameter every expansion is included; an empty value includes none.
+func parseIncludes(r *http.Request) (models.HotelIncludes, error) {
+	values, present := r.URL.Query()["include"]
+	if !present {
+		return models.HotelIncludes{Contacts: true, Officials: true}, nil
 	}
 
-	json.NewEncoder(w).Encode(officials)
+	var include models.HotelIncludes
+	for _, value := range strings.Split(strings.Join(values, ","), ",") {
+		switch strings.TrimSpace(value) {
+		case "":
+		case "contacts":
+			include.Contacts = true
+		case "officials":
+			include.Officials = true
+		default:
+			return include, fmt.Errorf("Invalid include %q, expected contacts or officials", value)
+		}
+	}
+	return include, nil
 }
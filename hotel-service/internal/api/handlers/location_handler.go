@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/service"
+)
+
+// LocationHandler handles location-related HTTP requests
+type LocationHandler struct {
+	service *service.LocationService // Service for location operations
+}
+
+// NewLocationHandler creates a new LocationHandler with the given service
+func NewLocationHandler(service *service.LocationService) *LocationHandler {
+	return &LocationHandler{service: service}
+}
+
+// ListLocations lists all locations ordered by name.
+func (h *LocationHandler) ListLocations(w http.ResponseWriter, r *http.Request) {
+	locations, err := h.service.ListLocations(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, locations)
+}
+
+// CreateLocation adds a new location.
+func (h *LocationHandler) CreateLocation(w http.ResponseWriter, r *http.Request) {
+	var location models.Location
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.CreateLocation(r.Context(), &location); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, location)
+}
+
+// GetLocation retrieves a location by ID.
+func (h *LocationHandler) GetLocation(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseLocationID(w, r)
+	if !ok {
+		return
+	}
+
+	location, err := h.service.GetLocation(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, location)
+}
+
+// UpdateLocation replaces a location by ID.
+func (h *LocationHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseLocationID(w, r)
+	if !ok {
+		return
+	}
+
+	var location models.Location
+	if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	location.ID = id // The path identifies the location, not the body
+
+	if err := h.service.UpdateLocation(r.Context(), &location); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, location)
+}
+
+// DeleteLocation removes a location by ID.
+func (h *LocationHandler) DeleteLocation(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseLocationID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteLocation(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseLocationID parses the location ID from the path, writing a problem response if it is invalid.
+func parseLocationID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid location ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/service"
+)
+
+// OfficialHandler handles official-related HTTP requests
+type OfficialHandler struct {
+	service *service.OfficialService // Service for official operations
+}
+
+// NewOfficialHandler creates a new OfficialHandler with the given service
+func NewOfficialHandler(service *service.OfficialService) *OfficialHandler {
+	return &OfficialHandler{service: service}
+}
+
+// ListOfficials lists the officials of a hotel by ID.
+func (h *OfficialHandler) ListOfficials(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := uuid.Parse(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID")
+		return
+	}
+
+	officials, err := h.service.ListOfficials(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, officials)
+}
+
+// AddOfficial adds an official to a hotel.
+func (h *OfficialHandler) AddOfficial(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	hotelID, err := uuid.Parse(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID")
+		return
+	}
+
+	var official models.Official
+	if err := json.NewDecoder(r.Body).Decode(&official); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	official.HotelID = hotelID // The path identifies the hotel, not the body
+
+	if err := h.service.AddOfficial(r.Context(), &official); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, official)
+}
+
+// RemoveOfficial removes an official from a hotel.
+func (h *OfficialHandler) RemoveOfficial(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	hotelID, err := uuid.Parse(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid hotel ID")
+		return
+	}
+	officialID, err := uuid.Parse(params["officialId"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid official ID")
+		return
+	}
+
+	if err := h.service.RemoveOfficial(r.Context(), hotelID, officialID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
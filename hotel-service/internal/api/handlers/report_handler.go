@@ -1,62 +1,205 @@
 package handlers
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/olivere/elastic/v7"
-	"github.com/tfgoztok/hotel-service/internal/messaging"
+	"github.com/tfgoztok/hotel-service/internal/health"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/service"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
 )
 
 // ReportHandler handles report-related requests
 type ReportHandler struct {
-	rabbitMQ messaging.RabbitMQInterface // Interface for RabbitMQ messaging
-	esClient *elastic.Client              // Elasticsearch client
+	service  *service.ReportService // Service for report requests
+	esClient *elastic.Client        // Elasticsearch client
+	health   *health.Registry       // Dependency states; indexing is skipped while Elasticsearch is down
+	logger   logger.Logger          // Logger for best-effort indexing failures
 }
 
 // NewReportHandler creates a new instance of ReportHandler
-func NewReportHandler(rabbitMQ messaging.RabbitMQInterface, esClient *elastic.Client) *ReportHandler {
-	return &ReportHandler{rabbitMQ: rabbitMQ, esClient: esClient}
+func NewReportHandler(service *service.ReportService, esClient *elastic.Client, health *health.Registry, logger logger.Logger) *ReportHandler {
+	return &ReportHandler{service: service, esClient: esClient, health: health, logger: logger}
 }
 
-// ReportRequest represents the structure of a report request
-type ReportRequest struct {
-    ID       uuid.UUID `json:"id"`       // Unique identifier for the report
-    Status   string    `json:"status"`   // Status of the report request
-    Location string    `json:"location"` // Location associated with the report
+// reportRequestBody is the body accepted by RequestReport
+type reportRequestBody struct {
+	Location string `json:"location"` // Location to report on
+}
+
+// reportRequestResponse is the body returned by RequestReport
+type reportRequestResponse struct {
+	*models.ReportRequest
+	Degraded []string `json:"degraded,omitempty"` // Optional dependencies that are down, e.g. rabbitmq delaying delivery
 }
 
 // RequestReport handles incoming report requests
 func (h *ReportHandler) RequestReport(w http.ResponseWriter, r *http.Request) {
-    var request ReportRequest
-    // Decode the JSON request body into the ReportRequest struct
-    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest) // Return error if decoding fails
-        return
-    }
-
-    request.ID = uuid.New() // Generate a new UUID for the report
-    request.Status = "pending" // Set the initial status of the report
-
-    // Publish the report request to the RabbitMQ queue
-    err := h.rabbitMQ.PublishReportRequest("report_requests", request)
-    if err != nil {
-        http.Error(w, "Failed to request report", http.StatusInternalServerError) // Handle publishing error
-        return
-    }
-
-    // Index the report request in Elasticsearch
-    _, err = h.esClient.Index().
-        Index("report_requests").
-        Id(request.ID.String()).
-        BodyJson(request).
-        Do(r.Context())
-    if err != nil {
-        http.Error(w, "Failed to index report request", http.StatusInternalServerError) // Handle indexing error
-        return
-    }
-
-    w.WriteHeader(http.StatusAccepted) // Respond with 202 Accepted status
-    json.NewEncoder(w).Encode(request) // Encode the request as JSON and send it in the response
+	var body reportRequestBody
+	// Decode the JSON request body
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request body") // Return error if decoding fails
+		return
+	}
+
+	// Persist the request; the outbox dispatcher publishes it to RabbitMQ
+	request, err := h.service.RequestReport(r.Context(), body.Location)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Index the report request in Elasticsearch. The request is already queued,
+	// so an indexing failure must not fail the response.
+	if h.esClient != nil && h.health.Up(health.Elasticsearch) {
+		_, err = h.esClient.Index().
+			Index("report_requests").
+			Id(request.ID.String()).
+			BodyJson(request).
+			Do(r.Context())
+		if err != nil {
+			h.logger.Error("Failed to index report request", "id", request.ID, "error", err)
+		}
+	}
+
+	// Respond with 202 Accepted and the queued request, which is delivered late while RabbitMQ is down
+	writeJSON(w, http.StatusAccepted, reportRequestResponse{ReportRequest: request, Degraded: h.health.Degraded()})
+}
+
+// LocationStats returns the hotel and phone counts of the ?location= synchronously
+func (h *ReportHandler) LocationStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.LocationStats(r.Context(), r.URL.Query().Get("location"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// LocationStatistics returns the statistics of every location, ordered by ?sort= (a field of
+// models.LocationStatisticsSortFields, prefixed with "-" for descending order). It responds with
+// CSV for ?format=csv or an Accept header asking for text/csv, and with JSON otherwise.
+func (h *ReportHandler) LocationStatistics(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	sort, ok := models.ParseLocationStatisticsSort(query.Get("sort"))
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid sort, expected one of "+strings.Join(models.LocationStatisticsSortFields, ", ")+", optionally prefixed with -")
+		return
+	}
+
+	var asCSV bool
+	switch query.Get("format") {
+	case "":
+		asCSV = strings.Contains(r.Header.Get("Accept"), "text/csv")
+	case "csv":
+		asCSV = true
+	case "json":
+	default:
+		writeProblem(w, r, http.StatusBadRequest, "Invalid format, expected csv or json")
+		return
+	}
+
+	stats, err := h.service.StatsByLocation(r.Context(), sort)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if asCSV {
+		writeLocationStatisticsCSV(w, stats)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// writeLocationStatisticsCSV writes the statistics as a CSV attachment with a header row.
+func writeLocationStatisticsCSV(w http.ResponseWriter, stats []*models.LocationStatistics) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="location-statistics.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"location", "hotel_count", "phone_count", "email_count", "last_updated_at"})
+	for _, s := range stats {
+		var lastUpdated string
+		if !s.LastUpdatedAt.IsZero() {
+			lastUpdated = s.LastUpdatedAt.UTC().Format(time.RFC3339)
+		}
+		out.Write([]string{
+			csvText(s.Location),
+			strconv.Itoa(s.HotelCount),
+			strconv.Itoa(s.PhoneCount),
+			strconv.Itoa(s.EmailCount),
+			lastUpdated,
+		})
+	}
+	out.Flush()
+}
+
+// csvText keeps user-provided text from being evaluated as a formula by spreadsheet applications.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// ListReports lists the most recent report requests, optionally filtered by ?status=
+func (h *ReportHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var filter models.ReportFilter
+
+	if v := query.Get("status"); v != "" {
+		status, ok := models.ParseReportStatus(v)
+		if !ok {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid status "+v)
+			return
+		}
+		filter.Status = status
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	requests, err := h.service.ListReports(r.Context(), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, requests)
+}
+
+// GetReport retrieves a report request and its current status by ID
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	id, err := uuid.Parse(params["id"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid report ID")
+		return
+	}
+
+	request, err := h.service.GetReport(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, request)
 }
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/tfgoztok/hotel-service/internal/domain"
+)
+
+// problem is an RFC 7807 problem details body.
+type problem struct {
+	Type     string              `json:"type"`               // URI reference identifying the problem type
+	Title    string              `json:"title"`              // Short summary of the problem type
+	Status   int                 `json:"status"`             // HTTP status code
+	Detail   string              `json:"detail,omitempty"`   // Explanation specific to this occurrence
+	Instance string              `json:"instance,omitempty"` // The request path the problem occurred on
+	Errors   []domain.FieldError `json:"errors,omitempty"`   // Field-level validation failures
+}
+
+// errorRecorder is implemented by response writers that keep the error behind a failed response,
+// such as the one installed by the logging middleware.
+type errorRecorder interface {
+	RecordError(err error)
+}
+
+// statusFor maps a domain error kind to its HTTP status code.
+func statusFor(kind domain.Kind) int {
+	switch kind {
+	case domain.KindNotFound:
+		return http.StatusNotFound
+	case domain.KindConflict:
+		return http.StatusConflict
+	case domain.KindValidation:
+		return http.StatusUnprocessableEntity
+	case domain.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case domain.KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case domain.KindBadRequest:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a problem+json response. Only the client-safe message of a
+// domain error is exposed; anything else is reported as an opaque internal error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if recorder, ok := w.(errorRecorder); ok {
+		recorder.RecordError(err) // Keep the full error for the request log
+	}
+
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) || domainErr.Kind == domain.KindInternal {
+		writeProblem(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	writeProblemFields(w, r, statusFor(domainErr.Kind), domainErr.Message, domainErr.Fields)
+}
+
+// writeProblem writes a problem+json response for the given status code and detail.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	writeProblemFields(w, r, status, detail, nil)
+}
+
+// writeProblemFields writes a problem+json response that also lists field-level errors.
+func writeProblemFields(w http.ResponseWriter, r *http.Request, status int, detail string, fields []domain.FieldError) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   fields,
+	})
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/service"
+)
+
+// SearchHandler handles full-text search requests
+type SearchHandler struct {
+	service *service.SearchService // Service for hotel search
+}
+
+// NewSearchHandler creates a new SearchHandler with the given service
+func NewSearchHandler(service *service.SearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+// SearchHotels returns the hotels matching ?q=, optionally narrowed to a ?location=, with
+// highlighted matches and location facet counts
+func (h *SearchHandler) SearchHotels(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	search := models.HotelSearchQuery{
+		Text:     query.Get("q"),
+		Location: query.Get("location"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		search.Limit = limit
+	}
+
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid offset")
+			return
+		}
+		search.Offset = offset
+	}
+
+	result, err := h.service.SearchHotels(r.Context(), search)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
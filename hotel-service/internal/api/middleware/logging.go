@@ -4,22 +4,52 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/tfgoztok/hotel-service/internal/tracing"
 	"github.com/tfgoztok/hotel-service/pkg/logger"
 )
 
+// responseRecorder wraps an http.ResponseWriter to capture the response status
+// and the error a handler reported through RecordError.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int   // Status code written by the handler
+	err    error // Error behind a failed response, if any
+}
+
+// WriteHeader records the status code before delegating to the wrapped writer.
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// RecordError keeps the error behind a failed response so it can be logged.
+func (rec *responseRecorder) RecordError(err error) {
+	rec.err = err
+}
+
 // Logging is a middleware function that logs HTTP requests.
 func Logging(l logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		// Return an http.HandlerFunc to handle the request
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()  // Record the start time of the request
-			next.ServeHTTP(w, r) // Call the next handler in the chain
+			start := time.Now()                                                // Record the start time of the request
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK} // Capture status and errors
+			next.ServeHTTP(rec, r)                                             // Call the next handler in the chain
 			// Log the request details including method, path, and duration
-			l.Info("Request processed",
+			fields := []interface{}{
 				"method", r.Method,
 				"path", r.URL.Path,
+				"status", rec.status,
 				"duration", time.Since(start),
-			)
+			}
+			if traceID := tracing.TraceID(r.Context()); traceID != "" {
+				fields = append(fields, "trace_id", traceID) // Finds the request's trace
+			}
+			if rec.err != nil {
+				l.Error("Request failed", append(fields, "error", rec.err)...)
+				return
+			}
+			l.Info("Request processed", fields...)
 		})
 	}
 }
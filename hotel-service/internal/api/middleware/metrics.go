@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tfgoztok/hotel-service/internal/metrics"
+)
+
+// Metrics wraps router to record the count and latency of HTTP requests, labeled with the
+// template of the matched route rather than the request path. It wraps the router rather than
+// being added with Use, which only runs for matched routes, so requests that match no route
+// are recorded too, as "unmatched".
+func Metrics(m *metrics.Metrics, router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unmatched"
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.Route != nil {
+			if template, err := match.Route.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		start := time.Now()
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		router.ServeHTTP(rec, r)
+		m.ObserveRequest(r.Method, route, rec.status, time.Since(start))
+	})
+}
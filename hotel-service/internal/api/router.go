@@ -9,49 +9,92 @@ import (
 	"github.com/tfgoztok/hotel-service/internal/api/graphql"
 	"github.com/tfgoztok/hotel-service/internal/api/handlers"
 	"github.com/tfgoztok/hotel-service/internal/api/middleware"
-	"github.com/tfgoztok/hotel-service/internal/messaging"
+	"github.com/tfgoztok/hotel-service/internal/health"
+	"github.com/tfgoztok/hotel-service/internal/metrics"
 	"github.com/tfgoztok/hotel-service/internal/repository"
 	"github.com/tfgoztok/hotel-service/internal/service"
 	"github.com/tfgoztok/hotel-service/pkg/logger"
 )
 
-func NewRouter(db *sql.DB, logger logger.Logger, rabbitMQ messaging.RabbitMQInterface, esClient *elastic.Client) http.Handler {
+// NewRouter wires the repositories, services and handlers and registers the routes.
+// Hotels are searched with searcher; a nil searcher disables search. The state of the
+// dependencies is read from registry, and requests and GraphQL operations are recorded in m.
+func NewRouter(db *sql.DB, logger logger.Logger, esClient *elastic.Client, searcher service.HotelSearcher, registry *health.Registry, m *metrics.Metrics) http.Handler {
 	// Create a new router instance
 	r := mux.NewRouter()
 
-	// Initialize repositories for hotels and contacts
+	// Initialize repositories for hotels, contacts, officials and locations
 	hotelRepo := repository.NewHotelRepository(db)
 	contactRepo := repository.NewContactRepository(db)
+	officialRepo := repository.NewOfficialRepository(db)
+	locationRepo := repository.NewLocationRepository(db)
 
-	// Initialize services for hotels and contacts
-	hotelService := service.NewHotelService(hotelRepo)
+	// Initialize services for hotels, contacts, officials and locations
+	hotelService := service.NewHotelService(hotelRepo, officialRepo)
 	contactService := service.NewContactService(contactRepo)
+	officialService := service.NewOfficialService(officialRepo)
+	locationService := service.NewLocationService(locationRepo)
 
-	// Initialize handlers for hotels and contacts
+	// Initialize handlers for hotels, contacts, officials and locations
 	hotelHandler := handlers.NewHotelHandler(hotelService)
 	contactHandler := handlers.NewContactHandler(contactService)
+	officialHandler := handlers.NewOfficialHandler(officialService)
+	locationHandler := handlers.NewLocationHandler(locationService)
 
-	// Initialize handler for elk
-	reportHandler := handlers.NewReportHandler(rabbitMQ, esClient)
+	// Initialize the report request flow; requests are published by the outbox dispatcher
+	reportService := service.NewReportService(repository.NewReportRepository(db), hotelRepo)
+	reportHandler := handlers.NewReportHandler(reportService, esClient, registry, logger)
 
-	graphqlService := graphql.NewGraphQLService(hotelService)
-	graphqlHandler, err := handlers.NewGraphQLHandler(graphqlService)
+	// Initialize hotel search on the configured backend
+	searchHandler := handlers.NewSearchHandler(service.NewSearchService(searcher))
+
+	graphqlService := graphql.NewGraphQLService(hotelService, reportService)
+	graphqlHandler, err := handlers.NewGraphQLHandler(graphqlService, graphql.NewMetricsExtension(m), graphql.NewTracingExtension())
 	if err != nil {
 		logger.Fatal("Failed to create GraphQL handler", "error", err)
 	}
 	r.Handle("/graphql", graphqlHandler)
 
-	// Middleware for logging requests
+	healthHandler := handlers.NewHealthHandler(registry)
+
+	// Middleware for tracing requests, outermost so the logging middleware sees the trace, and
+	// for logging requests. Request metrics wrap the router below.
+	r.Use(middleware.Tracing())
 	r.Use(middleware.Logging(logger))
 
+	// Define health routes
+	r.HandleFunc("/health", healthHandler.Health).Methods("GET")    // State of the service and its dependencies
+	r.HandleFunc("/healthz", healthHandler.Liveness).Methods("GET") // Process liveness
+	r.HandleFunc("/readyz", healthHandler.Readiness).Methods("GET") // Readiness, checking the dependencies
+	r.Handle("/metrics", m.Handler()).Methods("GET")                // Prometheus metrics
+
 	// Define routes for hotel operations
-	r.HandleFunc("/hotels", hotelHandler.CreateHotel).Methods("POST")                                 // Create a new hotel
-	r.HandleFunc("/hotels/{id}", hotelHandler.DeleteHotel).Methods("DELETE")                          // Delete a hotel by ID
-	r.HandleFunc("/hotels/{id}/contacts", contactHandler.AddContact).Methods("POST")                  // Add a contact to a hotel
-	r.HandleFunc("/hotels/{id}/contacts/{contactId}", contactHandler.DeleteContact).Methods("DELETE") // Delete a contact by ID
-	r.HandleFunc("/hotels/{id}/officials", hotelHandler.ListOfficials).Methods("GET")                 // List officials for a hotel
-	r.HandleFunc("/hotels/{id}", hotelHandler.GetHotelDetails).Methods("GET")                         // Get details of a hotel
-	r.HandleFunc("/reports/request", reportHandler.RequestReport).Methods("POST")                     // Request report from report-service
-
-	return r // Return the configured router
+	r.HandleFunc("/hotels", hotelHandler.CreateHotel).Methods("POST")                                     // Create a new hotel
+	r.HandleFunc("/hotels", hotelHandler.ListHotels).Methods("GET")                                       // List hotels with filters and cursor pagination
+	r.HandleFunc("/hotels/nearby", hotelHandler.NearbyHotels).Methods("GET")                              // Find hotels near a point; before /hotels/{id}
+	r.HandleFunc("/hotels/search", searchHandler.SearchHotels).Methods("GET")                             // Full-text hotel search; before /hotels/{id}
+	r.HandleFunc("/hotels/{id}", hotelHandler.DeleteHotel).Methods("DELETE")                              // Delete a hotel by ID
+	r.HandleFunc("/hotels/{id}", hotelHandler.UpdateHotel).Methods("PUT")                                 // Replace a hotel, honouring If-Match
+	r.HandleFunc("/hotels/{id}", hotelHandler.PatchHotel).Methods("PATCH")                                // Merge-patch a hotel, honouring If-Match
+	r.HandleFunc("/hotels/{id}/contacts", contactHandler.AddContact).Methods("POST")                      // Add a contact to a hotel
+	r.HandleFunc("/hotels/{id}/contacts", contactHandler.ListContacts).Methods("GET")                     // List a hotel's contacts, optionally by type
+	r.HandleFunc("/hotels/{id}/contacts/{contactId}", contactHandler.GetContact).Methods("GET")           // Get a contact of a hotel
+	r.HandleFunc("/hotels/{id}/contacts/{contactId}", contactHandler.PatchContact).Methods("PATCH")       // Merge-patch a contact of a hotel
+	r.HandleFunc("/hotels/{id}/contacts/{contactId}", contactHandler.DeleteContact).Methods("DELETE")     // Delete a contact of a hotel
+	r.HandleFunc("/hotels/{id}/officials", officialHandler.ListOfficials).Methods("GET")                  // List officials for a hotel
+	r.HandleFunc("/hotels/{id}/officials", officialHandler.AddOfficial).Methods("POST")                   // Add an official to a hotel
+	r.HandleFunc("/hotels/{id}/officials/{officialId}", officialHandler.RemoveOfficial).Methods("DELETE") // Remove an official from a hotel
+	r.HandleFunc("/hotels/{id}", hotelHandler.GetHotelDetails).Methods("GET")                             // Get details of a hotel
+	r.HandleFunc("/locations", locationHandler.CreateLocation).Methods("POST")                            // Create a location
+	r.HandleFunc("/locations", locationHandler.ListLocations).Methods("GET")                              // List locations by name
+	r.HandleFunc("/locations/{id}", locationHandler.GetLocation).Methods("GET")                           // Get a location
+	r.HandleFunc("/locations/{id}", locationHandler.UpdateLocation).Methods("PUT")                        // Replace a location, renaming its hotels
+	r.HandleFunc("/locations/{id}", locationHandler.DeleteLocation).Methods("DELETE")                     // Delete a location no hotel is assigned to
+	r.HandleFunc("/reports/request", reportHandler.RequestReport).Methods("POST")                         // Request report from report-service
+	r.HandleFunc("/reports", reportHandler.ListReports).Methods("GET")                                    // List report requests and their status
+	r.HandleFunc("/reports/location-stats", reportHandler.LocationStats).Methods("GET")                   // Compute location statistics synchronously
+	r.HandleFunc("/reports/{id}", reportHandler.GetReport).Methods("GET")                                 // Get a report request and its status; after the static /reports routes
+	r.HandleFunc("/stats/locations", reportHandler.LocationStatistics).Methods("GET")                     // Statistics of every location as JSON or CSV
+
+	return middleware.Metrics(m, r) // Return the configured router, recording request metrics
 }
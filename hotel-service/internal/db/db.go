@@ -2,16 +2,14 @@ package db
 
 import (
 	"database/sql"
-	_ "github.com/lib/pq"
+
+	"github.com/lib/pq"
+	"github.com/tfgoztok/hotel-service/internal/tracing"
 )
 
 func Connect(databaseURL string) (*sql.DB, error) {
-	// Attempt to open a connection to the database using the provided URL
-	db, err := sql.Open("postgres", databaseURL)
-	if err != nil {
-		// Return nil and the error if the connection could not be established
-		return nil, err
-	}
+	// Open the database through a connector that traces the statements of traced requests
+	db := sql.OpenDB(tracing.SQLConnector(&pq.Driver{}, databaseURL))
 
 	// Ping the database to verify that the connection is alive
 	if err := db.Ping(); err != nil {
@@ -1,15 +1,20 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
-// RunMigrations executes the database migrations from the specified path.
+// RunMigrations executes the database migrations from the specified path. A database already
+// migrated past the newest of them, by a newer release, is left as it is.
 func RunMigrations(db *sql.DB, migrationPath string) error {
 	// Create a new Postgres driver instance using the provided database connection.
 	driver, err := postgres.WithInstance(db, &postgres.Config{})
@@ -25,6 +30,15 @@ func RunMigrations(db *sql.DB, migrationPath string) error {
 		return fmt.Errorf("could not create the migration instance: %v", err)
 	}
 
+	// Up fails on a version missing from the path, so skip a database that is ahead of it
+	latest, err := LatestMigration(migrationPath)
+	if err != nil {
+		return err
+	}
+	if version, dirty, err := m.Version(); err == nil && !dirty && version > latest {
+		return nil
+	}
+
 	// Run the migrations. If there are no changes, it will not return an error.
 	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
 		return fmt.Errorf("could not run up migrations: %v", err)
@@ -33,3 +47,55 @@ func RunMigrations(db *sql.DB, migrationPath string) error {
 	// Return nil if migrations were successful or if there were no changes.
 	return nil
 }
+
+// LatestMigration returns the version of the newest migration in the specified path, i.e. the
+// version the database is at once every migration has run.
+func LatestMigration(migrationPath string) (uint, error) {
+	entries, err := os.ReadDir(migrationPath)
+	if err != nil {
+		return 0, fmt.Errorf("could not read the migrations: %v", err)
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		prefix, _, found := strings.Cut(entry.Name(), "_")
+		if !found || !strings.HasSuffix(entry.Name(), ".up.sql") {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		if uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("no migrations found in %s", migrationPath)
+	}
+	return latest, nil
+}
+
+// CheckMigrationVersion returns an error unless the database schema is at least at the given
+// version and its last migration completed. A newer schema is accepted, so an instance of the
+// previous release keeps serving while a rolling deployment migrates the database ahead of it.
+func CheckMigrationVersion(ctx context.Context, db *sql.DB, want uint) error {
+	var (
+		version uint
+		dirty   bool
+	)
+	err := db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no migration has run, want version %d", want)
+	}
+	if err != nil {
+		return fmt.Errorf("could not read the migration version: %v", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d did not complete", version)
+	}
+	if version < want {
+		return fmt.Errorf("database is at migration %d, want at least %d", version, want)
+	}
+	return nil
+}
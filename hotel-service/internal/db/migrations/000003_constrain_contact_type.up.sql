@@ -0,0 +1,31 @@
+-- Normalize free-form contact types to the canonical set (see models.ContactType).
+UPDATE contacts SET type = CASE upper(trim(type))
+    WHEN 'TEL' THEN 'PHONE'
+    WHEN 'TELEPHONE' THEN 'PHONE'
+    WHEN 'MOBILE' THEN 'PHONE'
+    WHEN 'CELL' THEN 'PHONE'
+    WHEN 'GSM' THEN 'PHONE'
+    WHEN 'E-MAIL' THEN 'EMAIL'
+    WHEN 'MAIL' THEN 'EMAIL'
+    WHEN 'GEO' THEN 'LOCATION'
+    WHEN 'WEB' THEN 'WEBSITE'
+    WHEN 'URL' THEN 'WEBSITE'
+    WHEN 'TELEFAX' THEN 'FAX'
+    ELSE upper(trim(type))
+END;
+
+-- Enforce the set for all new writes. Rows that could not be classified are left for manual
+-- review instead of failing the migration; the constraint is validated once none remain.
+ALTER TABLE contacts
+    ADD CONSTRAINT contacts_type_check
+    CHECK (type IN ('PHONE', 'EMAIL', 'LOCATION', 'WEBSITE', 'FAX', 'SOCIAL')) NOT VALID;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM contacts WHERE type NOT IN ('PHONE', 'EMAIL', 'LOCATION', 'WEBSITE', 'FAX', 'SOCIAL')
+    ) THEN
+        ALTER TABLE contacts VALIDATE CONSTRAINT contacts_type_check;
+    END IF;
+END
+$$;
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS officials (
+    id UUID PRIMARY KEY,
+    hotel_id UUID NOT NULL REFERENCES hotels(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    surname VARCHAR(100) NOT NULL,
+    role VARCHAR(30) NOT NULL CHECK (role IN ('PRIMARY', 'OWNER', 'GENERAL_MANAGER', 'SALES_DIRECTOR', 'OTHER')),
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    active_from DATE,
+    active_to DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (active_from IS NULL OR active_to IS NULL OR active_to >= active_from)
+);
+
+CREATE INDEX IF NOT EXISTS officials_hotel_id_idx ON officials (hotel_id);
+
+-- Every hotel has exactly one PRIMARY official mirroring hotels.official_name/official_surname,
+-- which stay on the hotel record for API compatibility.
+CREATE UNIQUE INDEX IF NOT EXISTS officials_primary_idx ON officials (hotel_id) WHERE role = 'PRIMARY';
+
+INSERT INTO officials (id, hotel_id, name, surname, role, created_at, updated_at)
+SELECT gen_random_uuid(), id, official_name, official_surname, 'PRIMARY', created_at, updated_at
+FROM hotels;
@@ -0,0 +1,2 @@
+-- Published as the AMQP correlation id, e.g. the report request a message belongs to.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(100) NOT NULL DEFAULT '';
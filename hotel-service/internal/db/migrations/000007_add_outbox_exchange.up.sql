@@ -0,0 +1,2 @@
+-- Exchange a message is published to; '' is the default exchange, which routes by queue name.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS exchange VARCHAR(200) NOT NULL DEFAULT '';
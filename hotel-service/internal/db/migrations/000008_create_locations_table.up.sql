@@ -0,0 +1,51 @@
+CREATE EXTENSION IF NOT EXISTS unaccent;
+
+-- fold_location reduces a location name to the key locations are matched by: surrounding
+-- whitespace trimmed, inner whitespace collapsed, accents stripped and case folded, so
+-- 'Istanbul', ' istanbul' and 'İstanbul' share the key 'istanbul'. The Turkish dotted and
+-- dotless i are mapped explicitly since case folding alone keeps them apart.
+CREATE OR REPLACE FUNCTION fold_location(name TEXT) RETURNS TEXT AS $$
+    SELECT lower(public.unaccent('public.unaccent'::regdictionary,
+        translate(regexp_replace(btrim(name), '\s+', ' ', 'g'), 'İı', 'Ii')))
+$$ LANGUAGE SQL IMMUTABLE STRICT PARALLEL SAFE;
+
+CREATE TABLE IF NOT EXISTS locations (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    normalized_name TEXT GENERATED ALWAYS AS (fold_location(name)) STORED UNIQUE,
+    country VARCHAR(100) NOT NULL DEFAULT '',
+    city VARCHAR(100) NOT NULL DEFAULT '',
+    district VARCHAR(100) NOT NULL DEFAULT '',
+    latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+-- One location per distinct folded value. The spelling used by the most hotels becomes the
+-- canonical name; ties go to the spelling used first.
+INSERT INTO locations (id, name, created_at, updated_at)
+SELECT DISTINCT ON (key) gen_random_uuid(), spelling, first_used, first_used
+FROM (
+    SELECT fold_location(location) AS key,
+           regexp_replace(btrim(location), '\s+', ' ', 'g') AS spelling,
+           COUNT(*) AS hotels,
+           MIN(created_at) AS first_used
+    FROM hotels
+    GROUP BY 1, 2
+) spellings
+ORDER BY key, hotels DESC, first_used
+ON CONFLICT (normalized_name) DO NOTHING;
+
+-- hotels.location stays on the hotel record for API compatibility and mirrors the canonical name.
+ALTER TABLE hotels ADD COLUMN IF NOT EXISTS location_id UUID REFERENCES locations(id);
+
+UPDATE hotels h
+SET location_id = l.id, location = l.name
+FROM locations l
+WHERE l.normalized_name = fold_location(h.location);
+
+ALTER TABLE hotels ALTER COLUMN location_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS hotels_location_id_idx ON hotels (location_id);
@@ -0,0 +1,34 @@
+ALTER TABLE hotels
+    ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION CHECK (longitude BETWEEN -180 AND 180);
+
+-- Postgres has no ADD CONSTRAINT IF NOT EXISTS; check the catalog so a rerun does not fail.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conrelid = 'hotels'::regclass AND conname = 'hotels_coordinates_check'
+    ) THEN
+        ALTER TABLE hotels
+            ADD CONSTRAINT hotels_coordinates_check CHECK ((latitude IS NULL) = (longitude IS NULL));
+    END IF;
+END
+$$;
+
+-- Proximity searches filter on a bounding box before computing exact distances.
+CREATE INDEX IF NOT EXISTS hotels_coordinates_idx ON hotels (latitude, longitude) WHERE latitude IS NOT NULL;
+
+-- Seed the coordinates from each hotel's oldest LOCATION contact holding a "latitude,longitude" pair.
+UPDATE hotels h
+SET latitude = c.latitude, longitude = c.longitude
+FROM (
+    SELECT DISTINCT ON (hotel_id) hotel_id,
+           split_part(content, ',', 1)::DOUBLE PRECISION AS latitude,
+           split_part(content, ',', 2)::DOUBLE PRECISION AS longitude
+    FROM contacts
+    WHERE type = 'LOCATION' AND content ~ '^\s*-?[0-9]+(\.[0-9]+)?\s*,\s*-?[0-9]+(\.[0-9]+)?\s*$'
+    ORDER BY hotel_id, created_at, id
+) c
+WHERE c.hotel_id = h.id
+  AND c.latitude BETWEEN -90 AND 90
+  AND c.longitude BETWEEN -180 AND 180;
@@ -0,0 +1,11 @@
+-- Full-text search document of a hotel, used when PostgreSQL is the search backend. Text is
+-- folded like location names, so searches ignore case and accents. The company title weighs
+-- most, then the location, then the primary official's name.
+ALTER TABLE hotels
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple'::regconfig, fold_location(company_title)), 'A') ||
+        setweight(to_tsvector('simple'::regconfig, fold_location(location)), 'B') ||
+        setweight(to_tsvector('simple'::regconfig, fold_location(official_name || ' ' || official_surname)), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS hotels_search_vector_idx ON hotels USING GIN (search_vector);
@@ -0,0 +1,3 @@
+-- W3C trace context (traceparent, tracestate) of the request that queued a message, so its
+-- publish continues that trace; NULL for messages queued outside of a trace.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS trace_context JSONB;
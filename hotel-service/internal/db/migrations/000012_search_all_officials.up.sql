@@ -0,0 +1,41 @@
+-- Names of all officials of a hotel, primary official first. hotels.official_names keeps them
+-- current on every official write, so the generated search document can include them.
+CREATE OR REPLACE FUNCTION official_names_of(hotel UUID) RETURNS TEXT AS $$
+    SELECT COALESCE(string_agg(name || ' ' || surname, ' ' ORDER BY role = 'PRIMARY' DESC, created_at, id), '')
+    FROM officials
+    WHERE hotel_id = hotel
+$$ LANGUAGE SQL STABLE;
+
+ALTER TABLE hotels ADD COLUMN IF NOT EXISTS official_names TEXT NOT NULL DEFAULT '';
+
+CREATE OR REPLACE FUNCTION refresh_hotel_official_names() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE hotels SET official_names = official_names_of(id) WHERE id = NEW.hotel_id;
+    END IF;
+    IF TG_OP = 'DELETE' OR (TG_OP = 'UPDATE' AND OLD.hotel_id <> NEW.hotel_id) THEN
+        UPDATE hotels SET official_names = official_names_of(id) WHERE id = OLD.hotel_id;
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS officials_refresh_hotel_official_names ON officials;
+CREATE TRIGGER officials_refresh_hotel_official_names
+    AFTER INSERT OR DELETE OR UPDATE OF hotel_id, name, surname, role ON officials
+    FOR EACH ROW EXECUTE FUNCTION refresh_hotel_official_names();
+
+UPDATE hotels SET official_names = official_names_of(id);
+
+-- Rebuild the search document over all officials rather than the primary official only. The
+-- company title weighs most, then the location, then the officials' names.
+DROP INDEX IF EXISTS hotels_search_vector_idx;
+ALTER TABLE hotels DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE hotels
+    ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple'::regconfig, fold_location(company_title)), 'A') ||
+        setweight(to_tsvector('simple'::regconfig, fold_location(location)), 'B') ||
+        setweight(to_tsvector('simple'::regconfig, fold_location(official_names)), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS hotels_search_vector_idx ON hotels USING GIN (search_vector);
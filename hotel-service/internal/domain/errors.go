@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"errors"
+)
+
+// Kind classifies a domain error independently of the transport it is reported over.
+type Kind int
+
+const (
+	KindInternal           Kind = iota // Unexpected failure; details must not reach clients
+	KindNotFound                       // The requested resource does not exist
+	KindConflict                       // The request conflicts with the current state of a resource
+	KindValidation                     // The request is well-formed but semantically invalid
+	KindUnavailable                    // A dependency is temporarily unavailable
+	KindPreconditionFailed             // A conditional request did not match the current version
+	KindBadRequest                     // The request itself is malformed
+)
+
+// String returns a human-readable name for the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindValidation:
+		return "validation failed"
+	case KindUnavailable:
+		return "service unavailable"
+	case KindPreconditionFailed:
+		return "precondition failed"
+	case KindBadRequest:
+		return "bad request"
+	default:
+		return "internal error"
+	}
+}
+
+// FieldError describes why a single input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name of the offending field
+	Message string `json:"message"` // Why the value was rejected
+}
+
+// Error is an error carrying a Kind and a message that is safe to show to clients.
+type Error struct {
+	Kind    Kind         // Classification of the error
+	Message string       // Client-safe description
+	Fields  []FieldError // Per-field details for validation errors
+	Err     error        // Underlying cause, if any; never shown to clients
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause so errors.Is and errors.As can inspect it.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound returns an error reporting that a resource does not exist.
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+// Conflict returns an error reporting that a write conflicts with existing state.
+func Conflict(message string, err error) *Error {
+	return &Error{Kind: KindConflict, Message: message, Err: err}
+}
+
+// Validation returns an error reporting semantically invalid input.
+func Validation(message string) *Error {
+	return &Error{Kind: KindValidation, Message: message}
+}
+
+// InvalidFields returns a validation error listing every rejected field.
+func InvalidFields(fields []FieldError) *Error {
+	return &Error{Kind: KindValidation, Message: "request validation failed", Fields: fields}
+}
+
+// Unavailable returns an error reporting that a dependency cannot be reached.
+func Unavailable(message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Message: message, Err: err}
+}
+
+// PreconditionFailed returns an error reporting that a conditional write lost a race.
+func PreconditionFailed(message string) *Error {
+	return &Error{Kind: KindPreconditionFailed, Message: message}
+}
+
+// BadRequest returns an error reporting malformed input.
+func BadRequest(message string) *Error {
+	return &Error{Kind: KindBadRequest, Message: message}
+}
+
+// KindOf returns the Kind of the first domain error in err's chain, or KindInternal if there is none.
+func KindOf(err error) Kind {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Kind
+	}
+	return KindInternal
+}
+
+// IsNotFound reports whether err is a NotFound domain error.
+func IsNotFound(err error) bool {
+	return KindOf(err) == KindNotFound
+}
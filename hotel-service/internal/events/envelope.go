@@ -0,0 +1,86 @@
+// Package events defines the versioned envelope for events exchanged with other services and
+// the JSON Schemas their payloads must conform to.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/tracing"
+)
+
+// Type identifies an event and the version of its payload schema. A change that could break
+// consumers, such as renaming or removing a field, requires a new version.
+type Type struct {
+	Name    string
+	Version int
+}
+
+// String returns the type as "name.vN", the base name of its schema document.
+func (t Type) String() string {
+	return fmt.Sprintf("%s.v%d", t.Name, t.Version)
+}
+
+// Event types published by the service.
+var (
+	ReportRequested = Type{Name: "report.requested", Version: 1} // A location report was requested
+	HotelCreated    = Type{Name: "hotel.created", Version: 1}    // A hotel was created; payload is the hotel
+	HotelUpdated    = Type{Name: "hotel.updated", Version: 1}    // A hotel was replaced or patched; payload is the updated hotel
+	HotelDeleted    = Type{Name: "hotel.deleted", Version: 1}    // A hotel and its contacts were deleted
+	ContactAdded    = Type{Name: "contact.added", Version: 1}    // A contact was added; payload is the contact
+	ContactUpdated  = Type{Name: "contact.updated", Version: 1}  // A contact was patched; payload is the updated contact
+	ContactRemoved  = Type{Name: "contact.removed", Version: 1}  // A contact was removed
+	OfficialAdded   = Type{Name: "official.added", Version: 1}   // An official was added; payload is the official
+	OfficialRemoved = Type{Name: "official.removed", Version: 1} // An official was removed
+)
+
+// Envelope wraps every event published to other services.
+type Envelope struct {
+	Type       string          `json:"type"`               // Event name, e.g. report.requested
+	Version    int             `json:"version"`            // Version of the payload schema
+	ID         uuid.UUID       `json:"id"`                 // Unique event ID, usable for deduplication
+	OccurredAt time.Time       `json:"occurred_at"`        // When the event happened
+	TraceID    string          `json:"trace_id,omitempty"` // Trace of the request that caused the event
+	Payload    json.RawMessage `json:"payload"`            // Event data, valid against the type's schema
+}
+
+// New wraps payload in an envelope of the given type, validating the payload against the
+// type's schema so an event that would break consumers is never published.
+func New(ctx context.Context, eventType Type, payload interface{}) (*Envelope, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+	if err := validatePayload(eventType, data); err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		Type:       eventType.Name,
+		Version:    eventType.Version,
+		ID:         uuid.New(),
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		TraceID:    TraceIDFromContext(ctx),
+		Payload:    data,
+	}, nil
+}
+
+// traceIDKey is the context key of the trace ID.
+type traceIDKey struct{}
+
+// ContextWithTraceID returns a context whose events carry the given trace ID.
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceIDFromContext returns the trace ID stored in ctx, or else the ID of the OpenTelemetry
+// trace ctx belongs to, or "" if there is neither.
+func TraceIDFromContext(ctx context.Context) string {
+	if traceID, ok := ctx.Value(traceIDKey{}).(string); ok {
+		return traceID
+	}
+	return tracing.TraceID(ctx)
+}
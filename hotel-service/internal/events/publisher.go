@@ -0,0 +1,32 @@
+package events
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Publisher handles the events consumed from the hotel.events exchange, e.g. search.Indexer.
+// The service itself does not emit events through a Publisher: the repositories write them to
+// the outbox in the transaction of their change, see repository.Announcement, and the outbox
+// dispatcher publishes them.
+type Publisher interface {
+	Publish(ctx context.Context, event *Envelope) error
+}
+
+// HotelDeletedPayload is the payload of HotelDeleted.
+type HotelDeletedPayload struct {
+	ID uuid.UUID `json:"id"` // ID of the deleted hotel
+}
+
+// ContactRemovedPayload is the payload of ContactRemoved.
+type ContactRemovedPayload struct {
+	ID      uuid.UUID `json:"id"`       // ID of the removed contact
+	HotelID uuid.UUID `json:"hotel_id"` // Hotel the contact belonged to
+}
+
+// OfficialRemovedPayload is the payload of OfficialRemoved.
+type OfficialRemovedPayload struct {
+	ID      uuid.UUID `json:"id"`       // ID of the removed official
+	HotelID uuid.UUID `json:"hotel_id"` // Hotel the official belonged to
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// envelopeSchema is the schema document every envelope must conform to.
+const envelopeSchema = "envelope.json"
+
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// schemas holds the compiled schemas by document name, e.g. "report.requested.v1".
+var schemas = mustCompileSchemas()
+
+// mustCompileSchemas compiles the embedded schema documents; a broken document is a build defect.
+func mustCompileSchemas() map[string]*jsonschema.Schema {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	compiler.AssertFormat = true // Check uuid and date-time formats rather than only annotating them
+
+	files, err := fs.Glob(schemaFS, "schemas/*.json")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		data, err := schemaFS.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		if err := compiler.AddResource(file, bytes.NewReader(data)); err != nil {
+			panic(fmt.Sprintf("events: invalid schema %s: %v", file, err))
+		}
+	}
+
+	compiled := make(map[string]*jsonschema.Schema, len(files))
+	for _, file := range files {
+		schema, err := compiler.Compile(file)
+		if err != nil {
+			panic(fmt.Sprintf("events: invalid schema %s: %v", file, err))
+		}
+		compiled[strings.TrimSuffix(path.Base(file), ".json")] = schema
+	}
+	return compiled
+}
+
+// Schema returns the JSON Schema document of an event type's payload.
+func Schema(eventType Type) ([]byte, error) {
+	return schemaFS.ReadFile("schemas/" + eventType.String() + ".json")
+}
+
+// Validate checks a serialized envelope against the envelope schema and its payload against
+// the schema of its type and version.
+func Validate(data []byte) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid event JSON: %w", err)
+	}
+	if err := schemas[strings.TrimSuffix(envelopeSchema, ".json")].Validate(doc); err != nil {
+		return fmt.Errorf("invalid event envelope: %w", err)
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("invalid event envelope: %w", err)
+	}
+	return validatePayload(Type{Name: envelope.Type, Version: envelope.Version}, envelope.Payload)
+}
+
+// validatePayload checks a serialized payload against the schema of the event type.
+func validatePayload(eventType Type, payload []byte) error {
+	schema, ok := schemas[eventType.String()]
+	if !ok {
+		return fmt.Errorf("no schema registered for event %s", eventType)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CheckResult is the outcome of the readiness check of a dependency.
+type CheckResult struct {
+	Name      string  `json:"name"`
+	Required  bool    `json:"required"`        // Whether the service is not ready without it
+	Status    string  `json:"status"`          // StatusUp or StatusDown
+	LatencyMs float64 `json:"latency_ms"`      // Duration of the check in milliseconds
+	Error     string  `json:"error,omitempty"` // Cause of the failure while down
+}
+
+// Readiness is the outcome of checking every dependency.
+type Readiness struct {
+	Status    string        `json:"status"`     // StatusOK, StatusDegraded or StatusUnavailable
+	CheckedAt time.Time     `json:"checked_at"` // When the checks ran; results are reused for the cache TTL
+	Checks    []CheckResult `json:"checks"`
+}
+
+// Ready reports whether the service can serve requests, i.e. no required dependency is down
+// and it is not shutting down.
+func (r Readiness) Ready() bool {
+	return r.Status == StatusOK || r.Status == StatusDegraded
+}
+
+// Drain marks the service as shutting down. From then on readiness fails without running the
+// checks, so load balancers stop sending new requests while the current ones complete.
+func (r *Registry) Drain() {
+	r.readyMu.Lock()
+	defer r.readyMu.Unlock()
+	r.draining = true
+}
+
+// Ready checks every dependency concurrently, each within the check timeout, and returns the
+// results in registration order. A result younger than the cache TTL is returned instead, so
+// frequent probes do not load the dependencies. The checks outlive the cancellation of ctx so
+// an abandoned probe does not cache failures.
+func (r *Registry) Ready(ctx context.Context) Readiness {
+	r.readyMu.Lock()
+	defer r.readyMu.Unlock()
+	if r.draining {
+		return Readiness{Status: StatusShuttingDown, CheckedAt: time.Now().UTC(), Checks: []CheckResult{}}
+	}
+	if r.readiness != nil && time.Since(r.readiness.CheckedAt) < r.cacheTTL {
+		return *r.readiness
+	}
+
+	r.mu.RLock()
+	deps := append([]*Dependency(nil), r.deps...)
+	r.mu.RUnlock()
+
+	readiness := Readiness{Status: StatusOK, CheckedAt: time.Now().UTC(), Checks: make([]CheckResult, len(deps))}
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep *Dependency) {
+			defer wg.Done()
+			readiness.Checks[i] = r.run(ctx, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	for _, result := range readiness.Checks {
+		if result.Status == StatusUp {
+			continue
+		}
+		if result.Required {
+			readiness.Status = StatusUnavailable
+		} else if readiness.Status == StatusOK {
+			readiness.Status = StatusDegraded
+		}
+	}
+
+	r.readiness = &readiness
+	return readiness
+}
+
+// run performs the readiness check of a dependency.
+func (r *Registry) run(ctx context.Context, dep *Dependency) CheckResult {
+	result := CheckResult{Name: dep.name, Required: dep.required, Status: StatusUp}
+
+	start := time.Now()
+	var err error
+	if dep.check != nil {
+		checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.checkTimeout)
+		err = dep.check(checkCtx)
+		cancel()
+	} else {
+		err = dep.Err()
+	}
+	result.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,243 @@
+// Package health tracks the state of the services hotel-service depends on. Required
+// dependencies must be reachable for the service to work; without an optional one the
+// service keeps running in degraded mode.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+)
+
+// Names of the dependencies of hotel-service.
+const (
+	Postgres      = "postgres"
+	RabbitMQ      = "rabbitmq"
+	Elasticsearch = "elasticsearch"
+	Migrations    = "migrations" // Database schema at the version the service expects
+)
+
+// Default readiness settings.
+const (
+	DefaultCheckTimeout = 2 * time.Second // Bound of each readiness check
+	DefaultCacheTTL     = 5 * time.Second // How long a readiness result is reused
+)
+
+// Dependency states.
+const (
+	StatusUp   = "up"   // Reachable
+	StatusDown = "down" // Unreachable or not connected yet
+)
+
+// Overall service states.
+const (
+	StatusOK           = "ok"            // Every dependency is up
+	StatusDegraded     = "degraded"      // An optional dependency is down
+	StatusUnavailable  = "unavailable"   // A required dependency is down
+	StatusShuttingDown = "shutting_down" // The service is draining its requests before it exits
+)
+
+// errNotChecked is the error of a dependency whose state has not been reported yet.
+var errNotChecked = errors.New("not checked yet")
+
+// Dependency is the state of a service hotel-service depends on. It is safe for concurrent use.
+type Dependency struct {
+	name     string
+	required bool
+	check    func(ctx context.Context) error // Readiness check; nil to report the recorded state
+	logger   logger.Logger
+
+	mu    sync.RWMutex
+	up    bool
+	err   error     // Cause of the last failure while down
+	since time.Time // When the dependency entered its current state
+}
+
+// DependencyStatus is a snapshot of a Dependency.
+type DependencyStatus struct {
+	Name     string    `json:"name"`
+	Required bool      `json:"required"`        // Whether the service is unavailable without it
+	Status   string    `json:"status"`          // StatusUp or StatusDown
+	Error    string    `json:"error,omitempty"` // Cause of the last failure while down
+	Since    time.Time `json:"since"`           // When the dependency entered its current state
+}
+
+// Report is the state of the service and each of its dependencies.
+type Report struct {
+	Status       string             `json:"status"` // StatusOK, StatusDegraded or StatusUnavailable
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Registry holds the dependencies of the service.
+type Registry struct {
+	logger       logger.Logger
+	checkTimeout time.Duration // Bound of each readiness check
+	cacheTTL     time.Duration // How long a readiness result is reused
+
+	mu   sync.RWMutex
+	deps []*Dependency
+
+	readyMu   sync.Mutex // Serializes readiness checks so concurrent probes share one run
+	readiness *Readiness // Last readiness result, nil before the first check
+	draining  bool       // Set by Drain; guarded by readyMu
+}
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// WithCheckTimeout sets how long each readiness check may take.
+func WithCheckTimeout(d time.Duration) Option {
+	return func(r *Registry) { r.checkTimeout = d }
+}
+
+// WithCacheTTL sets how long a readiness result is reused before the checks run again.
+func WithCacheTTL(d time.Duration) Option {
+	return func(r *Registry) { r.cacheTTL = d }
+}
+
+// NewRegistry creates an empty Registry logging state changes to logger.
+func NewRegistry(logger logger.Logger, opts ...Option) *Registry {
+	r := &Registry{logger: logger, checkTimeout: DefaultCheckTimeout, cacheTTL: DefaultCacheTTL}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds a dependency. It starts out down until its state is first reported. Readiness
+// checks run check, or report the recorded state if check is nil.
+func (r *Registry) Register(name string, required bool, check func(ctx context.Context) error) *Dependency {
+	dep := &Dependency{name: name, required: required, check: check, logger: r.logger, err: errNotChecked, since: time.Now().UTC()}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deps = append(r.deps, dep)
+	return dep
+}
+
+// Report returns the state of every dependency in registration order.
+func (r *Registry) Report() Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Dependencies: make([]DependencyStatus, len(r.deps))}
+	for i, dep := range r.deps {
+		status := dep.Status()
+		report.Dependencies[i] = status
+		if status.Status == StatusUp {
+			continue
+		}
+		if status.Required {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// Up reports whether the named dependency is reachable. Dependencies that are not
+// registered are assumed to be.
+func (r *Registry) Up(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, dep := range r.deps {
+		if dep.name == name {
+			return dep.Up()
+		}
+	}
+	return true
+}
+
+// Degraded returns the names of the optional dependencies that are down.
+func (r *Registry) Degraded() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	for _, dep := range r.deps {
+		if !dep.required && !dep.Up() {
+			names = append(names, dep.name)
+		}
+	}
+	return names
+}
+
+// Up reports whether the dependency is reachable.
+func (d *Dependency) Up() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.up
+}
+
+// Set records the outcome of connecting to or checking the dependency: nil marks it up, an
+// error marks it down. Changes of state are logged.
+func (d *Dependency) Set(err error) {
+	d.mu.Lock()
+	changed := d.up != (err == nil) || d.err == errNotChecked
+	d.up, d.err = err == nil, err
+	if changed {
+		d.since = time.Now().UTC()
+	}
+	d.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	switch {
+	case err == nil:
+		d.logger.Info("Dependency is up", "dependency", d.name)
+	case d.required:
+		d.logger.Error("Required dependency is down", "dependency", d.name, "error", err)
+	default:
+		d.logger.Error("Optional dependency is down, running in degraded mode", "dependency", d.name, "error", err)
+	}
+}
+
+// Err returns nil while the dependency is up and the cause of its failure while it is down.
+func (d *Dependency) Err() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.up {
+		return nil
+	}
+	return d.err
+}
+
+// Status returns a snapshot of the dependency.
+func (d *Dependency) Status() DependencyStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	status := DependencyStatus{Name: d.name, Required: d.required, Status: StatusDown, Since: d.since}
+	if d.up {
+		status.Status = StatusUp
+	} else if d.err != nil {
+		status.Error = d.err.Error()
+	}
+	return status
+}
+
+// Monitor checks the dependency every interval and records the outcome until ctx is done.
+// The first check runs immediately. Each check is bounded by the interval.
+func Monitor(ctx context.Context, dep *Dependency, interval time.Duration, check func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		err := check(checkCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		dep.Set(err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/tracing"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+)
+
+// Default dispatcher settings.
+const (
+	DefaultDispatchInterval = time.Second      // How often the outbox is polled
+	DefaultDispatchBatch    = 50               // Messages claimed per poll
+	DefaultDispatchLease    = 30 * time.Second // How long a claimed message is hidden from other dispatchers
+	DefaultRetryBaseDelay   = time.Second      // Delay after the first failed attempt
+	DefaultRetryMaxDelay    = 5 * time.Minute  // Upper bound for the exponential retry delay
+)
+
+// OutboxStore is the persistence the dispatcher reads pending messages from.
+type OutboxStore interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxMessage, error)
+	MarkSent(ctx context.Context, id uuid.UUID) error
+	MarkFailed(ctx context.Context, id uuid.UUID, cause string, nextAttempt time.Time) error
+}
+
+// Dispatcher publishes outbox messages to RabbitMQ, giving at-least-once delivery:
+// a message is marked as sent only after it was published, so a crash in between
+// publishes it again.
+type Dispatcher struct {
+	store     OutboxStore       // Source of pending messages
+	publisher RabbitMQInterface // Destination broker
+	logger    logger.Logger     // Logger for publish failures
+	interval  time.Duration     // Poll interval
+	batch     int               // Messages claimed per poll
+	lease     time.Duration     // Claim lease
+	baseDelay time.Duration     // First retry delay
+	maxDelay  time.Duration     // Maximum retry delay
+}
+
+// NewDispatcher creates a Dispatcher with the default settings.
+func NewDispatcher(store OutboxStore, publisher RabbitMQInterface, logger logger.Logger) *Dispatcher {
+	return &Dispatcher{
+		store:     store,
+		publisher: publisher,
+		logger:    logger,
+		interval:  DefaultDispatchInterval,
+		batch:     DefaultDispatchBatch,
+		lease:     DefaultDispatchLease,
+		baseDelay: DefaultRetryBaseDelay,
+		maxDelay:  DefaultRetryMaxDelay,
+	}
+}
+
+// Run polls the outbox until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		// Drain full batches back to back, then wait for the next tick
+		if err := d.Flush(ctx); err != nil {
+			d.logger.Error("Failed to dispatch outbox messages", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush publishes batches of due messages back to back until fewer than a full batch are due.
+// Messages whose publish fails are rescheduled rather than retried, so Flush returns even while
+// the broker is down. It is called at shutdown so the messages queued by the last requests are
+// published before the connection closes.
+func (d *Dispatcher) Flush(ctx context.Context) error {
+	for {
+		n, err := d.DispatchPending(ctx)
+		if err != nil || n < d.batch {
+			return err
+		}
+	}
+}
+
+// DispatchPending claims one batch of due messages and publishes them.
+// It returns the number of messages claimed.
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	messages, err := d.store.Claim(ctx, d.batch, d.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range messages {
+		// Continue the trace of the request that queued the message
+		err := d.publisher.Publish(tracing.Extract(ctx, msg.TraceContext), Message{
+			Exchange:      msg.Exchange,
+			RoutingKey:    msg.Destination,
+			ID:            msg.ID.String(), // Lets consumers detect redeliveries
+			CorrelationID: msg.CorrelationID,
+			Timestamp:     msg.CreatedAt,
+			Body:          json.RawMessage(msg.Payload),
+		})
+		if err != nil {
+			next := time.Now().Add(d.retryDelay(msg.Attempts))
+			d.logger.Error("Failed to publish outbox message", "id", msg.ID, "attempts", msg.Attempts+1, "error", err)
+			if err := d.store.MarkFailed(ctx, msg.ID, err.Error(), next); err != nil {
+				return len(messages), err
+			}
+			continue
+		}
+		if err := d.store.MarkSent(ctx, msg.ID); err != nil {
+			return len(messages), err // The message is published again once its lease expires
+		}
+	}
+	return len(messages), nil
+}
+
+// retryDelay returns the exponential backoff delay after the given number of failed attempts.
+func (d *Dispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.baseDelay
+	for i := 0; i < attempts && delay < d.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.maxDelay {
+		delay = d.maxDelay
+	}
+	return delay
+}
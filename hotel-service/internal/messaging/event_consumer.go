@@ -0,0 +1,35 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+)
+
+// eventTimeout bounds how long handling a single hotel, contact or official event may take.
+const eventTimeout = 30 * time.Second
+
+// NewEventHandler returns a DeliveryHandler that passes the hotel, contact and official events
+// published to the hotel.events exchange to publisher, e.g. a search.Indexer. A failed event is
+// redelivered, so publisher must tolerate seeing an event more than once.
+func NewEventHandler(publisher events.Publisher, logger logger.Logger) DeliveryHandler {
+	return func(body []byte) error {
+		var event events.Envelope
+		if err := json.Unmarshal(body, &event); err != nil {
+			logger.Error("Discarding malformed event", "error", err)
+			return domain.BadRequest("malformed event")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+		defer cancel()
+		if err := publisher.Publish(ctx, &event); err != nil {
+			logger.Error("Failed to handle event", "id", event.ID, "type", event.Type, "error", err)
+			return err
+		}
+		return nil
+	}
+}
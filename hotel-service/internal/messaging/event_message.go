@@ -0,0 +1,25 @@
+package messaging
+
+import (
+	"encoding/json"
+
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+// EventMessage wraps an event in an outbox message. The Dispatcher publishes it to the
+// hotel.events exchange with the event type as routing key, so consumers bind queues to the
+// event types they need, e.g. "hotel.*".
+func EventMessage(event *events.Envelope) (*models.OutboxMessage, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OutboxMessage{
+		ID:          event.ID, // Published as the message ID
+		Exchange:    HotelEventsExchange,
+		Destination: event.Type,
+		Payload:     payload,
+		CreatedAt:   event.OccurredAt,
+	}, nil
+}
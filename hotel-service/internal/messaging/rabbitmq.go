@@ -1,76 +1,641 @@
 package messaging
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/streadway/amqp"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/tracing"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Default connection manager settings.
+const (
+	DefaultConfirmTimeout      = 5 * time.Second        // How long a publish waits for the broker's ack
+	DefaultReconnectMinBackoff = 500 * time.Millisecond // Delay before the first reconnect attempt
+	DefaultReconnectMaxBackoff = 30 * time.Second       // Upper bound for the reconnect delay
+
+	consumerPrefetch = 10 // Unacknowledged deliveries a consumer holds at once
+	confirmBuffer    = 16 // Confirmations the broker may send ahead of the confirmTracker
+)
+
+// ErrNotConnected is returned by publishes while the connection is being re-established.
+var ErrNotConnected = domain.Unavailable("RabbitMQ connection is not available", nil)
+
+// errClosed is returned by connect once Close was called.
+var errClosed = errors.New("RabbitMQ connection manager is closed")
+
+// DeliveryHandler processes the body of a consumed message. Returning nil acknowledges the
+// message. A permanent error drops it; other errors reject it for a delayed retry, and park it
+// once it failed ConsumerMaxDeliveryTries times, see Topology.WithRetries.
+type DeliveryHandler func(body []byte) error
+
+// Message is a message published to an exchange.
+type Message struct {
+	Exchange      string      // Destination exchange; "" is the default exchange, which routes to the queue named by the routing key
+	RoutingKey    string      // Queue name for the default exchange, event type for topic exchanges
+	ID            string      // Message ID; stays the same when the message is published again
+	CorrelationID string      // ID of the request or entity the message is about
+	Timestamp     time.Time   // When the message was created
+	Body          interface{} // Marshalled to JSON
+}
+
 // RabbitMQInterface defines the methods that our RabbitMQ implementation should have
 type RabbitMQInterface interface {
-	PublishReportRequest(queueName string, reportRequest interface{}) error
+	PublishReportRequest(ctx context.Context, queueName string, reportRequest interface{}) error
+	Publish(ctx context.Context, msg Message) error
+	Consume(queueName string, handler DeliveryHandler) error
 	Close()
 }
 
-// RabbitMQ struct implements the RabbitMQInterface
+// Option configures a RabbitMQ connection manager.
+type Option func(*RabbitMQ)
+
+// WithTopology replaces DefaultTopology with the given topology.
+func WithTopology(t Topology) Option {
+	return func(r *RabbitMQ) { r.topology = t }
+}
+
+// WithLogger sets the logger used to report connection losses and reconnects.
+func WithLogger(l logger.Logger) Option {
+	return func(r *RabbitMQ) { r.logger = l }
+}
+
+// WithConfirmTimeout sets how long a publish waits for the broker to confirm it.
+func WithConfirmTimeout(d time.Duration) Option {
+	return func(r *RabbitMQ) { r.confirmTimeout = d }
+}
+
+// WithReconnectBackoff sets the bounds of the exponential delay between reconnect attempts.
+func WithReconnectBackoff(min, max time.Duration) Option {
+	return func(r *RabbitMQ) { r.minBackoff, r.maxBackoff = min, max }
+}
+
+// WithConnectInBackground makes NewRabbitMQ succeed even if the broker is unreachable. The
+// connection is then established in the background with the reconnect backoff; until it is,
+// publishes fail with ErrNotConnected and consumers are subscribed once it is.
+func WithConnectInBackground() Option {
+	return func(r *RabbitMQ) { r.connectInBackground = true }
+}
+
+// WithConnectionListener registers a function called with nil whenever the connection is
+// established, and with the cause whenever it is lost or an attempt to establish it fails.
+func WithConnectionListener(fn func(err error)) Option {
+	return func(r *RabbitMQ) { r.listener = fn }
+}
+
+// WithPublishListener registers a function called after every publish with its destination
+// (the exchange, or the queue for the default exchange), how long it took including the wait
+// for the broker's confirmation, and its error.
+func WithPublishListener(fn func(destination string, elapsed time.Duration, err error)) Option {
+	return func(r *RabbitMQ) { r.publishListener = fn }
+}
+
+// RabbitMQ implements RabbitMQInterface as a connection manager. It declares its topology on
+// connect, watches the connection and its channels, reconnects with exponential backoff when
+// one of them closes and then re-declares the topology and re-subscribes consumers. Publishes
+// are serialized, so a RabbitMQ may be shared by concurrent HTTP handlers, and each publish
+// waits for the broker's confirmation. Reconnecting only holds up publishes while the new
+// connection is installed, not while the broker is dialed.
 type RabbitMQ struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
+	url            string
+	topology       Topology
+	logger         logger.Logger
+	confirmTimeout time.Duration
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
+
+	connectInBackground bool            // Keep trying if the first attempt fails
+	listener            func(err error) // Notified of connection changes, may be nil
+
+	publishListener func(destination string, elapsed time.Duration, err error) // Notified of publishes, may be nil
+
+	mu        sync.Mutex       // Serializes publishes and guards the fields below
+	conn      *amqp.Connection // Current connection, nil while reconnecting
+	channel   *amqp.Channel    // Publishing channel in confirm mode, nil while reconnecting
+	confirms  *confirmTracker  // Publisher confirmations of channel
+	nextTag   uint64           // Delivery tag of the next publish on channel
+	consumers []*consumer      // Consumers re-subscribed after every reconnect
+	closed    bool             // Set by Close; stops reconnecting
+
+	done chan struct{} // Closed by Close
+}
+
+// consumer is a queue subscription that survives reconnects.
+type consumer struct {
+	queue   string
+	handler DeliveryHandler
+	conn    *amqp.Connection // Connection of the current subscription
+	channel *amqp.Channel    // Channel of the current subscription
 }
 
-// NewRabbitMQ creates a new RabbitMQ instance
-func NewRabbitMQ(url string) (RabbitMQInterface, error) {
-	conn, err := amqp.Dial(url)
+// NewRabbitMQ creates a new RabbitMQ connection manager. The first connection attempt is
+// made synchronously, so an unreachable broker or invalid URL is reported immediately, unless
+// WithConnectInBackground is given.
+func NewRabbitMQ(url string, opts ...Option) (RabbitMQInterface, error) {
+	r := &RabbitMQ{
+		url:            url,
+		topology:       DefaultTopology(),
+		logger:         logger.New(),
+		confirmTimeout: DefaultConfirmTimeout,
+		minBackoff:     DefaultReconnectMinBackoff,
+		maxBackoff:     DefaultReconnectMaxBackoff,
+		done:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	if err := r.connect(); err != nil {
+		if !r.connectInBackground {
+			return nil, err
+		}
+		r.notify(err)
+		r.logger.Error("Failed to connect to RabbitMQ, retrying in the background", "error", err)
+		go r.reconnect()
+	}
+	return r, nil
+}
+
+// notify passes a connection change to the listener, if any.
+func (r *RabbitMQ) notify(err error) {
+	if r.listener != nil {
+		r.listener(err)
+	}
+}
+
+// connect dials the broker, declares the topology, opens the publishing channel in confirm
+// mode, re-subscribes the consumers and starts watching the new connection. It returns
+// errClosed if Close was called meanwhile.
+func (r *RabbitMQ) connect() error {
+	// Dial without holding the lock so publishes keep failing fast while the broker is down
+	conn, err := amqp.Dial(r.url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %v", err)
+		return fmt.Errorf("failed to connect to RabbitMQ: %v", err)
 	}
 
+	if err := r.topology.declare(conn, r.logger); err != nil {
+		conn.Close()
+		return err
+	}
 	ch, err := conn.Channel()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open a channel: %v", err)
+		conn.Close()
+		return fmt.Errorf("failed to open a channel: %v", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to enable publisher confirms: %v", err)
 	}
 
-	return &RabbitMQ{conn: conn, channel: ch}, nil
+	if err := r.activate(conn, ch); err != nil {
+		return err
+	}
+	r.notify(nil)
+	return nil
 }
 
-// PublishReportRequest publishes a report request to the specified queue
-func (r *RabbitMQ) PublishReportRequest(queueName string, reportRequest interface{}) error {
-	q, err := r.channel.QueueDeclare(
-		queueName,
-		false,
-		false,
-		false,
-		false,
-		nil,
+// activate re-subscribes the consumers on a new connection and makes it the current one.
+// Consumers are subscribed without holding r.mu, which is only held to install the
+// connection once every consumer, including one added meanwhile by Consume, is subscribed.
+func (r *RabbitMQ) activate(conn *amqp.Connection, ch *amqp.Channel) error {
+	for {
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			conn.Close()
+			return errClosed
+		}
+		var pending []*consumer
+		for _, c := range r.consumers {
+			if c.conn != conn {
+				pending = append(pending, c)
+			}
+		}
+		if len(pending) == 0 {
+			r.conn = conn
+			r.channel = ch
+			r.confirms = newConfirmTracker(ch.NotifyPublish(make(chan amqp.Confirmation, confirmBuffer)))
+			r.nextTag = 1
+			r.mu.Unlock()
+
+			go r.watch(conn, ch)
+			return nil
+		}
+		r.mu.Unlock()
+
+		channels := make([]*amqp.Channel, len(pending))
+		for i, c := range pending {
+			consumerChannel, err := c.subscribe(conn, r.logger)
+			if err != nil {
+				conn.Close()
+				return err
+			}
+			channels[i] = consumerChannel
+		}
+
+		r.mu.Lock()
+		for i, c := range pending {
+			c.conn, c.channel = conn, channels[i]
+		}
+		r.mu.Unlock()
+	}
+}
+
+// watch waits for the connection or its publishing channel to close and reconnects.
+func (r *RabbitMQ) watch(conn *amqp.Connection, ch *amqp.Channel) {
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	watchChannel(ch, conn, "publishing channel", r.logger)
+
+	var cause *amqp.Error
+	select {
+	case <-r.done:
+		return
+	case cause = <-connClosed:
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.conn, r.channel = nil, nil // Fail publishes fast until reconnected
+	r.mu.Unlock()
+
+	r.logger.Error("RabbitMQ connection lost, reconnecting", "error", cause)
+	if cause != nil {
+		r.notify(cause)
+	} else {
+		r.notify(errors.New("connection closed"))
+	}
+	r.reconnect()
+}
+
+// watchChannel closes conn once ch is closed by a channel exception, which leaves the
+// connection up, so the client starts over on a fresh connection and restores every channel.
+// Closing ch ourselves sends no error and leaves conn alone. what names ch in the log.
+func watchChannel(ch *amqp.Channel, conn *amqp.Connection, what string, logger logger.Logger) {
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		if cause, ok := <-closed; ok && cause != nil {
+			logger.Error("RabbitMQ channel closed, reconnecting", "channel", what, "error", cause)
+			conn.Close()
+		}
+	}()
+}
+
+// reconnect retries connect with exponential backoff until it succeeds or Close is called.
+func (r *RabbitMQ) reconnect() {
+	backoff := r.minBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-r.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		err := r.connect()
+		if err == nil {
+			r.logger.Info("RabbitMQ connection re-established", "attempts", attempt)
+			return
+		}
+		if errors.Is(err, errClosed) {
+			return
+		}
+
+		r.logger.Error("Failed to reconnect to RabbitMQ", "attempt", attempt, "error", err)
+		r.notify(err)
+		if backoff *= 2; backoff > r.maxBackoff {
+			backoff = r.maxBackoff
+		}
+	}
+}
+
+// PublishReportRequest publishes a report request to the specified queue with a new message
+// ID and waits for the broker to confirm it. The trace context of ctx travels in its headers.
+func (r *RabbitMQ) PublishReportRequest(ctx context.Context, queueName string, reportRequest interface{}) error {
+	return r.Publish(ctx, Message{RoutingKey: queueName, ID: uuid.NewString(), Timestamp: time.Now(), Body: reportRequest})
+}
+
+// Publish publishes a persistent message and waits for the broker to confirm it.
+// The destination exchange or queue must already exist, e.g. as part of the topology.
+// The publish is traced as a producer span of the trace of ctx, whose W3C trace context is
+// sent in the traceparent and tracestate headers so consumers can continue the trace.
+func (r *RabbitMQ) Publish(ctx context.Context, msg Message) error {
+	destination := msg.Exchange
+	if destination == "" {
+		destination = msg.RoutingKey // The default exchange routes to the queue of that name
+	}
+	ctx, span := tracing.Tracer().Start(ctx, destination+" send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKey.String("rabbitmq"),
+			semconv.MessagingDestinationKey.String(destination),
+			semconv.MessagingRabbitmqRoutingKeyKey.String(msg.RoutingKey),
+			semconv.MessagingMessageIDKey.String(msg.ID),
+			semconv.MessagingConversationIDKey.String(msg.CorrelationID),
+		),
 	)
+	defer span.End()
+
+	headers := amqp.Table{}
+	for key, value := range tracing.Inject(ctx) {
+		headers[key] = value
+	}
+
+	start := time.Now()
+	err := r.publish(msg, headers)
+	if r.publishListener != nil {
+		r.publishListener(destination, time.Since(start), err)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to declare a queue: %v", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
+	return err
+}
 
-	body, err := json.Marshal(reportRequest)
+// publish implements Publish, sending the message with the given headers.
+func (r *RabbitMQ) publish(msg Message, headers amqp.Table) error {
+	body, err := json.Marshal(msg.Body)
 	if err != nil {
-		return fmt.Errorf("failed to marshal report request: %v", err)
+		return fmt.Errorf("failed to marshal message: %v", err)
 	}
 
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.channel == nil {
+		return ErrNotConnected
+	}
+
+	// Wait for the confirmation before publishing, as it may arrive before Publish returns
+	tag := r.nextTag
+	confirmed := r.confirms.expect(tag)
 	err = r.channel.Publish(
-		"",
-		q.Name,
+		msg.Exchange,
+		msg.RoutingKey,
 		false,
 		false,
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+			Headers:       headers,
+			ContentType:   "application/json",
+			DeliveryMode:  amqp.Persistent, // Survive a broker restart in a durable queue
+			MessageId:     msg.ID,
+			CorrelationId: msg.CorrelationID,
+			Timestamp:     msg.Timestamp.UTC(),
+			Body:          body,
 		})
 	if err != nil {
+		r.confirms.forget(tag)
 		return fmt.Errorf("failed to publish a message: %v", err)
 	}
+	r.nextTag++
+
+	return r.awaitConfirm(tag, confirmed)
+}
+
+// awaitConfirm waits for the confirmation of the publish with the given delivery tag.
+// The caller must hold r.mu.
+func (r *RabbitMQ) awaitConfirm(tag uint64, confirmed <-chan bool) error {
+	timeout := time.NewTimer(r.confirmTimeout)
+	defer timeout.Stop()
+
+	select {
+	case ack, ok := <-confirmed:
+		if !ok {
+			return errors.New("channel closed before the publish was confirmed")
+		}
+		if !ack {
+			return errors.New("broker rejected the message")
+		}
+		return nil
+	case <-timeout.C:
+		r.confirms.forget(tag) // Its late confirmation is dropped
+		return fmt.Errorf("timed out after %s waiting for the broker to confirm the publish", r.confirmTimeout)
+	}
+}
+
+// confirmTracker reads the confirmations of a publishing channel and hands each one to the
+// publish waiting for its delivery tag. Confirmations nobody waits for any more, such as those
+// of publishes that timed out, are dropped, so they never back up into the amqp library and
+// stall the connection.
+type confirmTracker struct {
+	mu      sync.Mutex
+	waiting map[uint64]chan bool // Acks by delivery tag
+	closed  bool                 // Set once the channel closed; no confirmations follow
+}
+
+// newConfirmTracker starts reading the confirmations from NotifyPublish until it is closed.
+func newConfirmTracker(confirms <-chan amqp.Confirmation) *confirmTracker {
+	t := &confirmTracker{waiting: make(map[uint64]chan bool)}
+	go t.run(confirms)
+	return t
+}
+
+// run delivers confirmations until the channel closes, then fails the remaining waits.
+func (t *confirmTracker) run(confirms <-chan amqp.Confirmation) {
+	for confirm := range confirms {
+		t.mu.Lock()
+		confirmed, ok := t.waiting[confirm.DeliveryTag]
+		delete(t.waiting, confirm.DeliveryTag)
+		t.mu.Unlock()
+		if ok {
+			confirmed <- confirm.Ack // Buffered, so this never blocks
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for tag, confirmed := range t.waiting {
+		close(confirmed)
+		delete(t.waiting, tag)
+	}
+}
+
+// expect returns a channel receiving whether the publish with the given delivery tag was
+// acked. It is closed if the publishing channel closes first.
+func (t *confirmTracker) expect(tag uint64) <-chan bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	confirmed := make(chan bool, 1)
+	if t.closed {
+		close(confirmed)
+		return confirmed
+	}
+	t.waiting[tag] = confirmed
+	return confirmed
+}
+
+// forget stops waiting for the confirmation of a publish.
+func (t *confirmTracker) forget(tag uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.waiting, tag)
+}
+
+// Consume starts handling the messages of a queue declared by the topology in the background
+// on a dedicated channel. The subscription is restored after every reconnect. It returns once the
+// consumer is registered. While the connection is down the consumer is subscribed on reconnect.
+func (r *RabbitMQ) Consume(queueName string, handler DeliveryHandler) error {
+	c := &consumer{queue: queueName, handler: handler}
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return errClosed
+	}
+	conn := r.conn
+	r.consumers = append(r.consumers, c)
+	r.mu.Unlock()
+	if conn == nil {
+		return nil // Subscribed by activate
+	}
 
+	ch, err := c.subscribe(conn, r.logger)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.removeConsumer(c)
+		return err
+	}
+	if r.closed || r.conn != conn || c.conn != nil {
+		ch.Close() // The connection was replaced meanwhile and activate subscribes c on the new one
+		return nil
+	}
+	c.conn, c.channel = conn, ch
 	return nil
 }
 
-// Close closes the RabbitMQ connection and channel
+// removeConsumer removes a consumer whose subscription failed. The caller must hold r.mu.
+func (r *RabbitMQ) removeConsumer(c *consumer) {
+	for i, other := range r.consumers {
+		if other == c {
+			r.consumers = append(r.consumers[:i], r.consumers[i+1:]...)
+			return
+		}
+	}
+}
+
+// subscribe opens a channel on conn and starts delivering the queue's messages to the handler.
+// If the broker closes the channel, e.g. because the queue was deleted, conn is closed as well
+// so the connection manager reconnects and subscribes again.
+func (c *consumer) subscribe(conn *amqp.Connection, logger logger.Logger) (*amqp.Channel, error) {
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open a channel: %v", err)
+	}
+
+	// The topology owns the queue's arguments, so only check that it exists
+	q, err := ch.QueueDeclarePassive(c.queue, true, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to find queue %s: %v", c.queue, err)
+	}
+	if err := ch.Qos(consumerPrefetch, 0, false); err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to set prefetch: %v", err)
+	}
+
+	watchChannel(ch, conn, "consumer channel of "+c.queue, logger)
+	deliveries, err := ch.Consume(q.Name, "", false, false, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to register a consumer: %v", err)
+	}
+
+	go func() {
+		// The deliveries channel is closed when the channel or connection closes
+		for d := range deliveries {
+			err := c.handler(d.Body)
+			switch {
+			case err == nil:
+				d.Ack(false)
+			case !retryable(err):
+				d.Ack(false) // Rejecting it would dead-letter it for a retry
+			case previousAttempts(d.Headers, c.queue)+1 < ConsumerMaxDeliveryTries:
+				d.Nack(false, false) // Dead-lettered to the retry queue
+			default:
+				c.park(ch, d, err, logger)
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// park publishes a message that failed its final attempt with err to the consumer dead-letter
+// exchange and removes it from the queue. If it cannot be parked, it is rejected for another
+// retry instead.
+func (c *consumer) park(ch *amqp.Channel, d amqp.Delivery, err error, logger logger.Logger) {
+	logger.Error("Parking message that keeps failing", "queue", c.queue, "attempts", ConsumerMaxDeliveryTries, "error", err)
+	perr := ch.Publish(ConsumerDeadLetterExchange, c.queue, false, false, amqp.Publishing{
+		Headers:       d.Headers, // The x-death header records its history
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		MessageId:     d.MessageId,
+		CorrelationId: d.CorrelationId,
+		Timestamp:     d.Timestamp,
+		Body:          d.Body,
+	})
+	if perr != nil {
+		logger.Error("Failed to park message", "queue", c.queue, "error", perr)
+		d.Nack(false, false)
+		return
+	}
+	d.Ack(false)
+}
+
+// previousAttempts returns how often a message was rejected from queue before, as counted by
+// the broker in the x-death header each time it dead-letters the message to the retry exchange.
+func previousAttempts(headers amqp.Table, queue string) int {
+	deaths, _ := headers["x-death"].([]interface{})
+	for _, death := range deaths {
+		table, ok := death.(amqp.Table)
+		if !ok || table["queue"] != queue || table["reason"] != "rejected" {
+			continue
+		}
+		if count, ok := table["count"].(int64); ok {
+			return int(count)
+		}
+	}
+	return 0
+}
+
+// retryable reports whether a message that failed with err may succeed when redelivered.
+// Malformed or invalid messages and messages about unknown resources are dropped.
+func retryable(err error) bool {
+	switch domain.KindOf(err) {
+	case domain.KindBadRequest, domain.KindValidation, domain.KindNotFound:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close stops reconnecting and closes the consumers, the publishing channel and the connection
 func (r *RabbitMQ) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	close(r.done)
+
+	for _, c := range r.consumers {
+		if c.channel != nil {
+			c.channel.Close()
+		}
+	}
 	if r.channel != nil {
 		r.channel.Close()
 	}
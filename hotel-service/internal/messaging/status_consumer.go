@@ -0,0 +1,39 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+)
+
+// statusUpdateTimeout bounds how long applying a single status update may take.
+const statusUpdateTimeout = 10 * time.Second
+
+// ReportStatusUpdater applies report status updates, e.g. service.ReportService.
+type ReportStatusUpdater interface {
+	UpdateReportStatus(ctx context.Context, update models.ReportStatusUpdate) error
+}
+
+// NewReportStatusHandler returns a DeliveryHandler that applies the status updates the
+// report service publishes to the report_status queue.
+func NewReportStatusHandler(updater ReportStatusUpdater, logger logger.Logger) DeliveryHandler {
+	return func(body []byte) error {
+		var update models.ReportStatusUpdate
+		if err := json.Unmarshal(body, &update); err != nil {
+			logger.Error("Discarding malformed report status update", "error", err)
+			return domain.BadRequest("malformed report status update")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), statusUpdateTimeout)
+		defer cancel()
+		if err := updater.UpdateReportStatus(ctx, update); err != nil {
+			logger.Error("Failed to apply report status update", "id", update.ID, "status", update.Status, "error", err)
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,220 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+)
+
+// Names of the exchanges and queues in DefaultTopology.
+const (
+	ReportsExchange     = "hotel.reports"       // Topic exchange report requests are routed through
+	RetryExchange       = "hotel.reports.retry" // Dead-letter exchange of report_requests; feeds the retry queue
+	DeadLetterExchange  = "hotel.reports.dead"  // Exchange for messages that exhausted their retries
+	HotelEventsExchange = "hotel.events"        // Topic exchange for hotel and contact events, routed by event type
+
+	ConsumerRetryExchange      = "hotel.consumers.retry" // Dead-letter exchange of the queues consumed by the service
+	ConsumerDeadLetterExchange = "hotel.consumers.dead"  // Exchange the service parks messages in that exhausted their retries
+
+	ReportRequestsQueue   = "report_requests"       // Consumed by the report service
+	ReportRetryQueue      = "report_requests.retry" // Holds rejected requests until ReportRetryDelay passes
+	ReportDeadLetterQueue = "report_requests.dead"  // Parks requests for manual inspection
+	ReportStatusQueue     = "report_status"         // Status updates published by the report service
+	SearchIndexQueue      = "hotel_search_index"    // Hotel, contact and official events applied to the search index
+
+	ReportRetryDelay       = 30 * time.Second // How long a rejected report request waits before redelivery
+	ReportMaxDeliveryTries = 5                // Deliveries of a report request before the report service parks it
+
+	ConsumerRetryDelay       = 30 * time.Second // How long a message the service failed to handle waits before redelivery
+	ConsumerMaxDeliveryTries = 5                // Deliveries of a message before the service parks it
+)
+
+// Exchange describes an exchange to declare.
+type Exchange struct {
+	Name string
+	Kind string // direct, fanout, topic or headers
+}
+
+// Queue describes a durable queue to declare.
+type Queue struct {
+	Name      string
+	Arguments amqp.Table // Dead-lettering and TTL settings
+}
+
+// Binding binds a queue to an exchange.
+type Binding struct {
+	Queue      string
+	Exchange   string
+	RoutingKey string
+}
+
+// Topology is the set of exchanges, queues and bindings the service relies on.
+// Everything is durable, so queued messages survive a broker restart.
+type Topology struct {
+	Exchanges []Exchange
+	Queues    []Queue
+	Bindings  []Binding
+}
+
+// DefaultTopology returns the report request topology. A report request the report service
+// rejects is dead-lettered to the retry queue and, after ReportRetryDelay, dead-lettered back
+// to report_requests through the reports exchange. The report service counts the rejections
+// in the x-death header and publishes a request that failed ReportMaxDeliveryTries times to
+// the dead-letter exchange instead of rejecting it again. The report_status queue the service
+// consumes retries the same way, see WithRetries.
+func DefaultTopology() Topology {
+	t := Topology{
+		Exchanges: []Exchange{
+			{Name: ReportsExchange, Kind: amqp.ExchangeTopic},
+			{Name: RetryExchange, Kind: amqp.ExchangeTopic},
+			{Name: DeadLetterExchange, Kind: amqp.ExchangeTopic},
+			{Name: HotelEventsExchange, Kind: amqp.ExchangeTopic},
+			{Name: ConsumerRetryExchange, Kind: amqp.ExchangeDirect},
+			{Name: ConsumerDeadLetterExchange, Kind: amqp.ExchangeDirect},
+		},
+		Queues: []Queue{
+			{Name: ReportRequestsQueue, Arguments: amqp.Table{
+				"x-dead-letter-exchange": RetryExchange,
+			}},
+			{Name: ReportRetryQueue, Arguments: amqp.Table{
+				"x-message-ttl":          int32(ReportRetryDelay / time.Millisecond),
+				"x-dead-letter-exchange": ReportsExchange, // Keeps the routing key, so it lands in report_requests again
+			}},
+			{Name: ReportDeadLetterQueue},
+		},
+		Bindings: []Binding{
+			{Queue: ReportRequestsQueue, Exchange: ReportsExchange, RoutingKey: ReportRequestsQueue},
+			{Queue: ReportRetryQueue, Exchange: RetryExchange, RoutingKey: "#"},
+			{Queue: ReportDeadLetterQueue, Exchange: DeadLetterExchange, RoutingKey: "#"},
+		},
+	}
+	return t.WithRetries(ReportStatusQueue)
+}
+
+// WithRetries returns the topology extended by a queue the service consumes, along with the
+// queues that delay its failed messages and park those that keep failing. A message the
+// consumer rejects is dead-lettered through the consumer retry exchange into <queue>.retry and,
+// after ConsumerRetryDelay, dead-lettered back to the queue through the default exchange. The
+// consumer counts the rejections in the x-death header and publishes a message that failed
+// ConsumerMaxDeliveryTries times to the consumer dead-letter exchange, which parks it in
+// <queue>.dead, instead of rejecting it again.
+func (t Topology) WithRetries(queue string) Topology {
+	retryQueue, deadLetterQueue := queue+".retry", queue+".dead"
+	t.Queues = append(t.Queues,
+		Queue{Name: queue, Arguments: amqp.Table{
+			"x-dead-letter-exchange":    ConsumerRetryExchange,
+			"x-dead-letter-routing-key": queue, // Messages routed by event type still reach the retry queue
+		}},
+		Queue{Name: retryQueue, Arguments: amqp.Table{
+			"x-message-ttl":             int32(ConsumerRetryDelay / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue, // Back to this queue only, not to every queue bound like it
+		}},
+		Queue{Name: deadLetterQueue},
+	)
+	t.Bindings = append(t.Bindings,
+		Binding{Queue: retryQueue, Exchange: ConsumerRetryExchange, RoutingKey: queue},
+		Binding{Queue: deadLetterQueue, Exchange: ConsumerDeadLetterExchange, RoutingKey: queue},
+	)
+	return t
+}
+
+// WithSearchIndex returns the topology extended by the queue the search indexer consumes the
+// hotel, contact and official events from, which retries like report_status. Only services
+// indexing hotels declare it, so the events do not pile up in a queue nobody consumes.
+func (t Topology) WithSearchIndex() Topology {
+	t = t.WithRetries(SearchIndexQueue)
+	t.Bindings = append(t.Bindings,
+		Binding{Queue: SearchIndexQueue, Exchange: HotelEventsExchange, RoutingKey: "hotel.*"},
+		Binding{Queue: SearchIndexQueue, Exchange: HotelEventsExchange, RoutingKey: "contact.*"},
+		Binding{Queue: SearchIndexQueue, Exchange: HotelEventsExchange, RoutingKey: "official.*"},
+	)
+	return t
+}
+
+// declare declares the topology on a channel of its own. Declarations are idempotent, so this
+// runs on every (re)connect. A queue that already exists with other settings, such as the
+// non-durable report_requests queue of earlier releases, is recreated with recreateQueue.
+func (t Topology) declare(conn *amqp.Connection, logger logger.Logger) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %v", err)
+	}
+	defer func() { ch.Close() }()
+
+	for _, e := range t.Exchanges {
+		if err := ch.ExchangeDeclare(e.Name, e.Kind, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("failed to declare exchange %s: %v", e.Name, err)
+		}
+	}
+	for _, q := range t.Queues {
+		_, err := ch.QueueDeclare(q.Name, true, false, false, false, q.Arguments)
+		if isPreconditionFailed(err) {
+			// The failed declaration closed the channel
+			if ch, err = conn.Channel(); err != nil {
+				return fmt.Errorf("failed to open a channel: %v", err)
+			}
+			logger.Info("Recreating queue declared with other settings", "queue", q.Name)
+			err = recreateQueue(ch, q)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to declare queue %s: %v", q.Name, err)
+		}
+	}
+	for _, b := range t.Bindings {
+		if err := ch.QueueBind(b.Queue, b.RoutingKey, b.Exchange, false, nil); err != nil {
+			return fmt.Errorf("failed to bind queue %s to %s: %v", b.Queue, b.Exchange, err)
+		}
+	}
+	return nil
+}
+
+// recreateQueue deletes an existing queue and declares it again with the settings of q. The
+// messages ready in the old queue are moved to the new one; they are held in memory meanwhile,
+// so a crash before they are published again loses them, as a broker restart would have lost
+// them from a non-durable queue. Consumers of the old queue are cancelled.
+func recreateQueue(ch *amqp.Channel, q Queue) error {
+	var messages []amqp.Delivery
+	for {
+		d, ok, err := ch.Get(q.Name, false) // Unacknowledged messages are discarded with the queue
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		messages = append(messages, d)
+	}
+
+	if _, err := ch.QueueDelete(q.Name, false, false, false); err != nil {
+		return err
+	}
+	if _, err := ch.QueueDeclare(q.Name, true, false, false, false, q.Arguments); err != nil {
+		return err
+	}
+	for _, d := range messages {
+		err := ch.Publish("", q.Name, false, false, amqp.Publishing{
+			Headers:       d.Headers,
+			ContentType:   d.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			MessageId:     d.MessageId,
+			CorrelationId: d.CorrelationId,
+			Timestamp:     d.Timestamp,
+			Body:          d.Body,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isPreconditionFailed reports whether err is the channel exception the broker raises when a
+// queue is declared with settings other than those it exists with.
+func isPreconditionFailed(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.PreconditionFailed
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+)
+
+// HotelCounter counts the hotels, e.g. repository.HotelRepository.
+type HotelCounter interface {
+	Count(ctx context.Context) (int64, error)
+}
+
+// ContactCounter counts the contacts by type, e.g. repository.ContactRepository.
+type ContactCounter interface {
+	CountByType(ctx context.Context) (map[models.ContactType]int64, error)
+}
+
+// DefaultInventoryTimeout bounds the queries of an InventoryCollector.
+const DefaultInventoryTimeout = 5 * time.Second
+
+var (
+	hotelsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "hotels"),
+		"Number of hotels.",
+		nil, nil,
+	)
+	contactsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "contacts"),
+		"Number of hotel contacts, by contact type.",
+		[]string{"type"}, nil,
+	)
+)
+
+// InventoryCollector reports business gauges, counted in the database whenever the metrics
+// are scraped. A count that fails is logged and left out of the scrape, so the remaining
+// metrics are still served while the database is unreachable.
+type InventoryCollector struct {
+	hotels   HotelCounter
+	contacts ContactCounter
+	timeout  time.Duration // Bound of the queries of one scrape
+	logger   logger.Logger
+}
+
+// NewInventoryCollector creates an InventoryCollector counting with hotels and contacts.
+func NewInventoryCollector(hotels HotelCounter, contacts ContactCounter, logger logger.Logger) *InventoryCollector {
+	return &InventoryCollector{hotels: hotels, contacts: contacts, timeout: DefaultInventoryTimeout, logger: logger}
+}
+
+// Describe implements prometheus.Collector.
+func (c *InventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- hotelsDesc
+	ch <- contactsDesc
+}
+
+// Collect implements prometheus.Collector. Every contact type is reported, with zero for
+// types without contacts.
+func (c *InventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if hotels, err := c.hotels.Count(ctx); err != nil {
+		c.logger.Error("Failed to count hotels for metrics", "error", err)
+	} else {
+		ch <- prometheus.MustNewConstMetric(hotelsDesc, prometheus.GaugeValue, float64(hotels))
+	}
+
+	contacts, err := c.contacts.CountByType(ctx)
+	if err != nil {
+		c.logger.Error("Failed to count contacts for metrics", "error", err)
+		return
+	}
+	for _, t := range models.ContactTypes {
+		ch <- prometheus.MustNewConstMetric(contactsDesc, prometheus.GaugeValue, float64(contacts[t]), string(t))
+	}
+}
@@ -0,0 +1,137 @@
+// Package metrics exposes the Prometheus metrics of hotel-service: HTTP requests, the database
+// connection pool, RabbitMQ publishes, GraphQL operations and resolvers, and hotel inventory.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes the names of the metrics defined by hotel-service.
+const namespace = "hotel_service"
+
+// Outcome label values.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Metrics holds the collectors of the service and the registry they are exposed from. It is
+// safe for concurrent use.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests      *prometheus.CounterVec   // By method, route and status
+	httpDuration      *prometheus.HistogramVec // By method, route and status
+	publishes         *prometheus.CounterVec   // By destination and outcome
+	publishDuration   *prometheus.HistogramVec // By destination
+	graphqlOperations *prometheus.HistogramVec // By root field and outcome
+	graphqlResolvers  *prometheus.HistogramVec // By field and outcome
+}
+
+// New creates the metrics of the service along with the Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by method, route template and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		publishes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rabbitmq_publishes_total",
+			Help:      "Messages published to RabbitMQ, by exchange or queue and outcome.",
+		}, []string{"destination", "outcome"}),
+		publishDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rabbitmq_publish_duration_seconds",
+			Help:      "Time taken to publish a message to RabbitMQ and receive its confirmation, by exchange or queue.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"destination"}),
+		graphqlOperations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "graphql_operation_duration_seconds",
+			Help:      "Time taken to execute GraphQL operations, by root field and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "outcome"}),
+		graphqlResolvers: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "graphql_resolver_duration_seconds",
+			Help:      "Time taken by the resolvers of root GraphQL fields, by field and outcome.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"field", "outcome"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.publishes,
+		m.publishDuration,
+		m.graphqlOperations,
+		m.graphqlResolvers,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Register adds a collector, e.g. an InventoryCollector, to the exposed metrics.
+func (m *Metrics) Register(c prometheus.Collector) error {
+	return m.registry.Register(c)
+}
+
+// RegisterDB exposes the connection pool statistics of a database as go_sql_* gauges and
+// counters labeled with name.
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// ObserveRequest records a handled HTTP request. route is the template of the matched route,
+// e.g. /hotels/{id}, so requests for different resources share their series.
+func (m *Metrics) ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(method, route, code).Inc()
+	m.httpDuration.WithLabelValues(method, route, code).Observe(elapsed.Seconds())
+}
+
+// ObservePublish records a publish to an exchange or queue and whether the broker confirmed it.
+func (m *Metrics) ObservePublish(destination string, elapsed time.Duration, err error) {
+	m.publishes.WithLabelValues(destination, outcome(err == nil)).Inc()
+	m.publishDuration.WithLabelValues(destination).Observe(elapsed.Seconds())
+}
+
+// ObserveGraphQLOperation records an executed GraphQL operation; failed is set if its result
+// holds errors.
+func (m *Metrics) ObserveGraphQLOperation(operation string, elapsed time.Duration, failed bool) {
+	m.graphqlOperations.WithLabelValues(operation, outcome(!failed)).Observe(elapsed.Seconds())
+}
+
+// ObserveGraphQLResolver records the resolution of a GraphQL field, named Type.field.
+func (m *Metrics) ObserveGraphQLResolver(field string, elapsed time.Duration, err error) {
+	m.graphqlResolvers.WithLabelValues(field, outcome(err == nil)).Observe(elapsed.Seconds())
+}
+
+// outcome returns the outcome label value of a success or failure.
+func outcome(ok bool) string {
+	if ok {
+		return OutcomeSuccess
+	}
+	return OutcomeFailure
+}
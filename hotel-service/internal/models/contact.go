@@ -1,16 +1,68 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ContactType classifies the content of a contact.
+type ContactType string
+
+const (
+	ContactTypePhone    ContactType = "PHONE"    // Telephone number in E.164 format
+	ContactTypeEmail    ContactType = "EMAIL"    // Email address
+	ContactTypeLocation ContactType = "LOCATION" // "latitude,longitude" coordinates
+	ContactTypeWebsite  ContactType = "WEBSITE"  // Absolute http(s) URL
+	ContactTypeFax      ContactType = "FAX"      // Fax number in E.164 format
+	ContactTypeSocial   ContactType = "SOCIAL"   // Social media profile URL or handle
+)
+
+// ContactTypes lists every valid contact type.
+var ContactTypes = []ContactType{
+	ContactTypePhone,
+	ContactTypeEmail,
+	ContactTypeLocation,
+	ContactTypeWebsite,
+	ContactTypeFax,
+	ContactTypeSocial,
+}
+
+// contactTypeAliases maps the spellings found in legacy data and client input to their canonical type.
+// Keep in sync with the backfill in 000003_constrain_contact_type.up.sql.
+var contactTypeAliases = map[string]ContactType{
+	"PHONE":     ContactTypePhone,
+	"TEL":       ContactTypePhone,
+	"TELEPHONE": ContactTypePhone,
+	"MOBILE":    ContactTypePhone,
+	"CELL":      ContactTypePhone,
+	"GSM":       ContactTypePhone,
+	"EMAIL":     ContactTypeEmail,
+	"E-MAIL":    ContactTypeEmail,
+	"MAIL":      ContactTypeEmail,
+	"LOCATION":  ContactTypeLocation,
+	"GEO":       ContactTypeLocation,
+	"WEBSITE":   ContactTypeWebsite,
+	"WEB":       ContactTypeWebsite,
+	"URL":       ContactTypeWebsite,
+	"FAX":       ContactTypeFax,
+	"TELEFAX":   ContactTypeFax,
+	"SOCIAL":    ContactTypeSocial,
+}
+
+// ParseContactType resolves a case-insensitive contact type or one of its aliases.
+// It reports false if the value does not name a known type.
+func ParseContactType(s string) (ContactType, bool) {
+	t, ok := contactTypeAliases[strings.ToUpper(strings.TrimSpace(s))]
+	return t, ok
+}
+
 type Contact struct {
-	ID        uuid.UUID `json:"id"`
-	HotelID   uuid.UUID `json:"hotel_id"`
-	Type      string    `json:"type"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uuid.UUID   `json:"id"`
+	HotelID   uuid.UUID   `json:"hotel_id"`
+	Type      ContactType `json:"type"`
+	Content   string      `json:"content"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
 }
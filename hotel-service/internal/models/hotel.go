@@ -12,12 +12,59 @@ type Hotel struct {
 	OfficialSurname string    `json:"official_surname"`
 	CompanyTitle    string    `json:"company_title"`
 	Location        string    `json:"location"`
+	Latitude        *float64  `json:"latitude,omitempty"`  // Latitude in degrees; set together with Longitude
+	Longitude       *float64  `json:"longitude,omitempty"` // Longitude in degrees
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
 
-type HotelOfficials struct {
-	HotelID         uuid.UUID `json:"hotel_id"`
-	OfficialName    string    `json:"official_name"`
-	OfficialSurname string    `json:"official_surname"`
+// HotelDetails is a hotel together with the related data a caller asked to include.
+type HotelDetails struct {
+	*Hotel
+	Contacts  map[ContactType][]*Contact `json:"contacts,omitempty"`  // Contacts grouped by type
+	Officials []*Official                `json:"officials,omitempty"` // Officials of the hotel
+}
+
+// HotelIncludes selects the expansions returned with hotel details.
+type HotelIncludes struct {
+	Contacts  bool
+	Officials bool
+}
+
+// HotelCursor identifies a position in the hotel listing keyset.
+type HotelCursor struct {
+	CreatedAt  time.Time
+	ID         uuid.UUID
+	Descending bool // Order of the listing the position belongs to
+}
+
+// HotelFilter holds the filtering, ordering and paging options for listing hotels.
+type HotelFilter struct {
+	Location           string       // Exact location match
+	CompanyTitlePrefix string       // Case-insensitive company title prefix
+	CreatedFrom        *time.Time   // Inclusive lower bound for created_at
+	CreatedTo          *time.Time   // Exclusive upper bound for created_at
+	Descending         bool         // Order by newest first
+	After              *HotelCursor // Keyset position to continue from
+	Limit              int          // Maximum number of rows to return
+}
+
+// HotelPage is a single page of hotels along with the cursor for the next page.
+type HotelPage struct {
+	Hotels     []*Hotel `json:"hotels"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// NearbyFilter selects the hotels within a radius of a point.
+type NearbyFilter struct {
+	Latitude  float64 // Latitude of the center in degrees
+	Longitude float64 // Longitude of the center in degrees
+	RadiusKm  float64 // Search radius in kilometers
+	Limit     int     // Maximum number of hotels to return
+}
+
+// NearbyHotel is a hotel found by a proximity search together with its distance from the center.
+type NearbyHotel struct {
+	*Hotel
+	DistanceKm float64 `json:"distance_km"` // Great-circle distance in kilometers
 }
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Location is a place hotels are located in. Hotels reference it by ID and mirror its
+// name in Hotel.Location; names are matched case- and accent-insensitively.
+type Location struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`               // Canonical name
+	Country   string    `json:"country,omitempty"`  // Country name
+	City      string    `json:"city,omitempty"`     // City name
+	District  string    `json:"district,omitempty"` // District within the city
+	Latitude  *float64  `json:"latitude,omitempty"` // Latitude in degrees; set together with Longitude
+	Longitude *float64  `json:"longitude,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OfficialRole describes the position an official holds at a hotel.
+type OfficialRole string
+
+const (
+	OfficialRolePrimary        OfficialRole = "PRIMARY"         // The official registered on the hotel record
+	OfficialRoleOwner          OfficialRole = "OWNER"           // Owner of the hotel
+	OfficialRoleGeneralManager OfficialRole = "GENERAL_MANAGER" // General manager
+	OfficialRoleSalesDirector  OfficialRole = "SALES_DIRECTOR"  // Director of sales
+	OfficialRoleOther          OfficialRole = "OTHER"           // Any other position, described by the title
+)
+
+// OfficialRoles lists every valid official role.
+var OfficialRoles = []OfficialRole{
+	OfficialRolePrimary,
+	OfficialRoleOwner,
+	OfficialRoleGeneralManager,
+	OfficialRoleSalesDirector,
+	OfficialRoleOther,
+}
+
+// Official is a person holding a position at a hotel.
+type Official struct {
+	ID         uuid.UUID    `json:"id"`
+	HotelID    uuid.UUID    `json:"hotel_id"`
+	Name       string       `json:"name"`
+	Surname    string       `json:"surname"`
+	Role       OfficialRole `json:"role"`
+	Title      string       `json:"title,omitempty"`
+	ActiveFrom *Date        `json:"active_from,omitempty"`
+	ActiveTo   *Date        `json:"active_to,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// dateLayout is the ISO 8601 calendar date format used for Date values.
+const dateLayout = "2006-01-02"
+
+// Date is a calendar date without a time of day, serialized as YYYY-MM-DD.
+type Date struct {
+	time.Time
+}
+
+// MarshalJSON encodes the date as a YYYY-MM-DD string.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(dateLayout))
+}
+
+// UnmarshalJSON decodes a YYYY-MM-DD string.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	d.Time = t
+	return nil
+}
+
+// Value implements driver.Valuer so dates can be stored in DATE columns.
+func (d Date) Value() (driver.Value, error) {
+	return d.Format(dateLayout), nil
+}
+
+// Scan implements sql.Scanner for DATE columns.
+func (d *Date) Scan(src interface{}) error {
+	t, ok := src.(time.Time)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Date", src)
+	}
+	d.Time = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return nil
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxMessage is a message persisted in the transactional outbox, waiting to be published.
+type OutboxMessage struct {
+	ID            uuid.UUID         // Unique identifier of the message
+	Exchange      string            // Exchange the message is published to; "" for the default exchange
+	Destination   string            // Routing key: the queue name for the default exchange
+	CorrelationID string            // ID of the request or entity the message is about
+	Payload       []byte            // JSON message body
+	TraceContext  map[string]string // W3C trace context of the request that queued the message, nil outside of a trace
+	Attempts      int               // Number of failed publish attempts so far
+	CreatedAt     time.Time         // When the message was written
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReportStatus is the lifecycle state of a report request.
+type ReportStatus string
+
+const (
+	ReportStatusPending    ReportStatus = "pending"    // Persisted and queued for the report service
+	ReportStatusProcessing ReportStatus = "processing" // Picked up by the report service
+	ReportStatusCompleted  ReportStatus = "completed"  // Report generated successfully
+	ReportStatusFailed     ReportStatus = "failed"     // Report generation failed
+)
+
+// ReportRequest represents a request for a location report.
+type ReportRequest struct {
+	ID        uuid.UUID    `json:"id"`       // Unique identifier for the report
+	Status    ReportStatus `json:"status"`   // Status of the report request
+	Location  string       `json:"location"` // Location associated with the report
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// ReportStatuses lists the valid report statuses in lifecycle order.
+var ReportStatuses = []ReportStatus{ReportStatusPending, ReportStatusProcessing, ReportStatusCompleted, ReportStatusFailed}
+
+// ParseReportStatus parses a case-insensitive report status.
+func ParseReportStatus(s string) (ReportStatus, bool) {
+	status := ReportStatus(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range ReportStatuses {
+		if status == known {
+			return status, true
+		}
+	}
+	return "", false
+}
+
+// PrecedingStatuses returns the statuses a request may move to s from. Statuses only move
+// forward, so a late or redelivered update never overwrites a newer one.
+func (s ReportStatus) PrecedingStatuses() []ReportStatus {
+	switch s {
+	case ReportStatusProcessing:
+		return []ReportStatus{ReportStatusPending}
+	case ReportStatusCompleted, ReportStatusFailed:
+		return []ReportStatus{ReportStatusPending, ReportStatusProcessing} // The processing update may be lost or late
+	default:
+		return nil
+	}
+}
+
+// ReportStatusUpdate is the message the report service publishes when a request changes status.
+type ReportStatusUpdate struct {
+	ID     uuid.UUID `json:"id"`     // Report request ID
+	Status string    `json:"status"` // New status, parsed with ParseReportStatus
+}
+
+// ReportFilter narrows a report request listing.
+type ReportFilter struct {
+	Status ReportStatus // Only requests in this status, if set
+	Limit  int          // Maximum number of requests
+}
@@ -0,0 +1,32 @@
+package models
+
+// LocationFacetSize is the largest number of locations counted in the location facet.
+const LocationFacetSize = 20
+
+// HotelSearchQuery is a full-text search for hotels.
+type HotelSearchQuery struct {
+	Text     string // Words matched fuzzily against company title, official names, location and contacts
+	Location string // Only return hotels in this location, e.g. a value of the location facet
+	Limit    int    // Maximum number of hits to return
+	Offset   int    // Number of hits to skip
+}
+
+// HotelSearchHit is a hotel matching a search together with its relevance.
+type HotelSearchHit struct {
+	*Hotel
+	Score      float64             `json:"score"`                // Relevance; higher is better
+	Highlights map[string][]string `json:"highlights,omitempty"` // Matched fragments by field, with matches wrapped in <em>
+}
+
+// FacetCount is the number of hits sharing a field value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// HotelSearchResult is a page of search hits with facet counts over all matching hotels.
+type HotelSearchResult struct {
+	Total  int64                   `json:"total"`  // Number of matching hotels
+	Hits   []*HotelSearchHit       `json:"hits"`   // Requested page of hits, most relevant first
+	Facets map[string][]FacetCount `json:"facets"` // Counts by field, e.g. "location"; not narrowed by the location filter
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// LocationStats summarizes the hotels of a location, as computed by the report service.
+type LocationStats struct {
+	Location   string `json:"location"`    // Location the statistics are for
+	HotelCount int    `json:"hotel_count"` // Number of hotels in the location
+	PhoneCount int    `json:"phone_count"` // Number of phone contacts of those hotels
+}
+
+// LocationStatistics summarizes the hotels of one location for the all-locations report.
+type LocationStatistics struct {
+	Location      string    `json:"location"`        // Location the statistics are for
+	HotelCount    int       `json:"hotel_count"`     // Number of hotels in the location
+	PhoneCount    int       `json:"phone_count"`     // Number of phone contacts of those hotels
+	EmailCount    int       `json:"email_count"`     // Number of email contacts of those hotels
+	LastUpdatedAt time.Time `json:"last_updated_at"` // Latest change to a hotel of the location or one of its contacts
+}
+
+// LocationStatisticsSortFields lists the fields the all-locations report can be sorted by.
+var LocationStatisticsSortFields = []string{"location", "hotel_count", "phone_count", "email_count", "last_updated_at"}
+
+// LocationStatisticsSort orders the all-locations report; ties are broken by location.
+type LocationStatisticsSort struct {
+	Field      string // One of LocationStatisticsSortFields
+	Descending bool   // Sort in descending order
+}
+
+// ParseLocationStatisticsSort parses a sort field, prefixed with "-" for descending order.
+// An empty string sorts by location.
+func ParseLocationStatisticsSort(s string) (LocationStatisticsSort, bool) {
+	sort := LocationStatisticsSort{Field: strings.TrimPrefix(s, "-"), Descending: strings.HasPrefix(s, "-")}
+	if sort.Field == "" {
+		return LocationStatisticsSort{Field: "location"}, s == ""
+	}
+	for _, field := range LocationStatisticsSortFields {
+		if field == sort.Field {
+			return sort, true
+		}
+	}
+	return sort, false
+}
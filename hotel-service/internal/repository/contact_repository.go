@@ -5,6 +5,8 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tfgoztok/hotel-service/internal/domain"
 	"github.com/tfgoztok/hotel-service/internal/models"
 )
 
@@ -19,38 +21,122 @@ func NewContactRepository(db *sql.DB) *ContactRepository {
 }
 
 // Create inserts a new contact into the database.
-// It takes a context for managing request-scoped values and a pointer to a Contact model.
-func (r *ContactRepository) Create(ctx context.Context, contact *models.Contact) error {
+// It takes a context for managing request-scoped values, a pointer to a Contact model and the
+// announcement whose message is written in the same transaction.
+func (r *ContactRepository) Create(ctx context.Context, contact *models.Contact, announce Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "contact")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
 	query := `
 		INSERT INTO contacts (id, hotel_id, type, content, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 	// Execute the insert query with the contact's details.
-	_, err := r.db.ExecContext(ctx, query, contact.ID, contact.HotelID, contact.Type, contact.Content, contact.CreatedAt, contact.UpdatedAt)
-	return err // Return any error encountered during execution.
+	if _, err := tx.ExecContext(ctx, query, contact.ID, contact.HotelID, contact.Type, contact.Content, contact.CreatedAt, contact.UpdatedAt); err != nil {
+		return translateError(err, "contact") // Return any error encountered during execution.
+	}
+	if err := announce.write(ctx, tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), "contact")
+}
+
+// Update overwrites the type and content of a contact belonging to the given hotel, and writes
+// the message of announce in the same transaction. The contact's created_at is refreshed from
+// the database.
+func (r *ContactRepository) Update(ctx context.Context, contact *models.Contact, announce Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "contact")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	query := `
+		UPDATE contacts
+		SET type = $3, content = $4, updated_at = $5
+		WHERE id = $1 AND hotel_id = $2
+		RETURNING created_at
+	`
+	// Execute the update query and read back the creation timestamp.
+	if err := tx.QueryRowContext(ctx, query, contact.ID, contact.HotelID, contact.Type, contact.Content, contact.UpdatedAt).Scan(&contact.CreatedAt); err != nil {
+		return translateError(err, "contact") // Return any error encountered during execution.
+	}
+	if err := announce.write(ctx, tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), "contact")
 }
 
 // Delete removes a contact from the database by its ID.
-// It takes a context and the UUID of the contact to be deleted.
-func (r *ContactRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM contacts WHERE id = $1`
-	// Execute the delete query using the provided contact ID.
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err // Return any error encountered during execution.
+// It takes a context, the UUID of the hotel the contact must belong to, the UUID of the contact to be deleted
+// and the announcement whose message is written in the same transaction.
+func (r *ContactRepository) Delete(ctx context.Context, hotelID, id uuid.UUID, announce Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "contact")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	query := `DELETE FROM contacts WHERE id = $1 AND hotel_id = $2`
+	// Execute the delete query using the provided contact and hotel IDs.
+	result, err := tx.ExecContext(ctx, query, id, hotelID)
+	if err != nil {
+		return translateError(err, "contact") // Return any error encountered during execution.
+	}
+	if err := expectAffected(result, "contact"); err != nil {
+		return err // Report a missing contact as not found.
+	}
+	if err := announce.write(ctx, tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), "contact")
 }
 
-// GetByHotelID retrieves all contacts associated with a specific hotel ID.
-// It returns a slice of pointers to Contact models and an error if any occurs.
-func (r *ContactRepository) GetByHotelID(ctx context.Context, hotelID uuid.UUID) ([]*models.Contact, error) {
+// GetByID retrieves a single contact by its ID, provided it belongs to the given hotel.
+func (r *ContactRepository) GetByID(ctx context.Context, hotelID, id uuid.UUID) (*models.Contact, error) {
+	query := `
+		SELECT id, hotel_id, type, content, created_at, updated_at
+		FROM contacts
+		WHERE id = $1 AND hotel_id = $2
+	`
+	var contact models.Contact // Variable to hold the retrieved contact.
+	// Execute the query and scan the row into the contact variable.
+	err := r.db.QueryRowContext(ctx, query, id, hotelID).Scan(
+		&contact.ID, &contact.HotelID, &contact.Type, &contact.Content, &contact.CreatedAt, &contact.UpdatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err, "contact") // Return nil and the error if the contact was not found.
+	}
+	return &contact, nil
+}
+
+// GetByHotelID retrieves all contacts associated with a specific hotel ID,
+// optionally restricted to the given contact types.
+// It returns a slice of pointers to Contact models, or a NotFound error if the hotel does not exist.
+func (r *ContactRepository) GetByHotelID(ctx context.Context, hotelID uuid.UUID, types ...models.ContactType) ([]*models.Contact, error) {
 	query := `
 		SELECT id, hotel_id, type, content, created_at, updated_at
 		FROM contacts
 		WHERE hotel_id = $1
 	`
+	args := []interface{}{hotelID}
+	if len(types) > 0 {
+		names := make([]string, len(types))
+		for i, t := range types {
+			names[i] = string(t)
+		}
+		query += ` AND type = ANY($2)`
+		args = append(args, pq.StringArray(names))
+	}
+	query += ` ORDER BY created_at, id`
+
 	// Execute the query to fetch contacts for the specified hotel ID.
-	rows, err := r.db.QueryContext(ctx, query, hotelID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err // Return nil and the error if the query fails.
+		return nil, translateError(err, "contact") // Return nil and the error if the query fails.
 	}
 	defer rows.Close() // Ensure rows are closed after processing.
 
@@ -64,5 +150,70 @@ func (r *ContactRepository) GetByHotelID(ctx context.Context, hotelID uuid.UUID)
 		}
 		contacts = append(contacts, &contact) // Append the contact to the slice.
 	}
-	return contacts, nil // Return the slice of contacts and nil for no error.
+	if err := rows.Err(); err != nil || len(contacts) > 0 {
+		return contacts, err // Return the slice of contacts and any iteration error.
+	}
+
+	// No contacts were found: tell a hotel without contacts apart from a missing hotel
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM hotels WHERE id = $1)`, hotelID).Scan(&exists); err != nil {
+		return nil, translateError(err, "hotel")
+	}
+	if !exists {
+		return nil, domain.NotFound("hotel not found")
+	}
+	return contacts, nil
+}
+
+// CountByType returns the number of contacts of each type. Types without contacts are omitted.
+func (r *ContactRepository) CountByType(ctx context.Context) (map[models.ContactType]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT type, COUNT(*) FROM contacts GROUP BY type`)
+	if err != nil {
+		return nil, translateError(err, "contact")
+	}
+	defer rows.Close()
+
+	counts := make(map[models.ContactType]int64)
+	for rows.Next() {
+		var (
+			contactType models.ContactType
+			count       int64
+		)
+		if err := rows.Scan(&contactType, &count); err != nil {
+			return nil, err
+		}
+		counts[contactType] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetByHotelIDs retrieves the contacts of several hotels at once, grouped by hotel ID.
+func (r *ContactRepository) GetByHotelIDs(ctx context.Context, hotelIDs []uuid.UUID) (map[uuid.UUID][]*models.Contact, error) {
+	query := `
+		SELECT id, hotel_id, type, content, created_at, updated_at
+		FROM contacts
+		WHERE hotel_id = ANY($1::uuid[])
+		ORDER BY created_at, id
+	`
+	ids := make([]string, len(hotelIDs))
+	for i, id := range hotelIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, pq.StringArray(ids))
+	if err != nil {
+		return nil, translateError(err, "contact")
+	}
+	defer rows.Close()
+
+	contacts := make(map[uuid.UUID][]*models.Contact)
+	for rows.Next() {
+		var contact models.Contact
+		err := rows.Scan(&contact.ID, &contact.HotelID, &contact.Type, &contact.Content, &contact.CreatedAt, &contact.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		contacts[contact.HotelID] = append(contacts[contact.HotelID], &contact)
+	}
+	return contacts, rows.Err()
 }
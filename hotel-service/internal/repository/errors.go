@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+)
+
+// Postgres error codes translated into domain errors.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqCheckViolation      = "23514"
+	pqNotNullViolation    = "23502"
+	pqStringTooLong       = "22001"
+)
+
+// translateError converts database errors into domain errors. entity names the resource
+// the statement operated on and is used in client-facing messages.
+func translateError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NotFound(entity + " not found")
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqUniqueViolation:
+			return domain.Conflict(entity+" already exists", err)
+		case pqForeignKeyViolation:
+			return &domain.Error{Kind: domain.KindNotFound, Message: entity + " references a resource that does not exist", Err: err}
+		case pqCheckViolation, pqNotNullViolation, pqStringTooLong:
+			return &domain.Error{Kind: domain.KindValidation, Message: entity + " is invalid", Err: err}
+		}
+		switch pqErr.Code.Class() {
+		case "08", "53", "57": // Connection exception, insufficient resources, operator intervention
+			return domain.Unavailable("database unavailable", err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return domain.Unavailable("database unavailable", err)
+	}
+	return err
+}
+
+// expectAffected returns a NotFound error for entity when a statement changed no rows.
+func expectAffected(result sql.Result, entity string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return domain.NotFound(entity + " not found")
+	}
+	return nil
+}
@@ -3,11 +3,21 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tfgoztok/hotel-service/internal/domain"
 	"github.com/tfgoztok/hotel-service/internal/models"
 )
 
+// ErrVersionConflict is returned when a hotel was modified after the version the caller based its update on.
+var ErrVersionConflict = domain.PreconditionFailed("hotel has been modified since it was last read")
+
 // HotelRepository is a struct that holds the database connection.
 type HotelRepository struct {
 	db *sql.DB // Database connection
@@ -18,60 +28,244 @@ func NewHotelRepository(db *sql.DB) *HotelRepository {
 	return &HotelRepository{db: db} // Return a new instance of HotelRepository
 }
 
-// Create inserts a new hotel record into the database.
-func (r *HotelRepository) Create(ctx context.Context, hotel *models.Hotel) error {
+// upsertLocation is a common table expression resolving the location name in $5 to its
+// location, creating the location if no name folds to the same key. The no-op update makes
+// RETURNING yield an existing location as well.
+const upsertLocation = `
+	resolved_location AS (
+		INSERT INTO locations (id, name, created_at, updated_at)
+		VALUES (gen_random_uuid(), $5, $6, $6)
+		ON CONFLICT (normalized_name) DO UPDATE SET name = locations.name
+		RETURNING id, name
+	)`
+
+// Create inserts a new hotel record into the database together with its primary official.
+// The hotel is assigned to the location its name resolves to, and hotel.Location is set to
+// that location's canonical name. The message of announce is written in the same transaction.
+func (r *HotelRepository) Create(ctx context.Context, hotel *models.Hotel, announce Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "hotel")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
 	query := `
-		INSERT INTO hotels (id, official_name, official_surname, company_title, location, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		WITH ` + upsertLocation + `, hotel AS (
+			INSERT INTO hotels (id, official_name, official_surname, company_title, location, location_id, latitude, longitude, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, (SELECT name FROM resolved_location), (SELECT id FROM resolved_location), $8, $9, $6, $7)
+			RETURNING id, official_name, official_surname, location, created_at, updated_at
+		), primary_official AS (
+			INSERT INTO officials (id, hotel_id, name, surname, role, created_at, updated_at)
+			SELECT gen_random_uuid(), id, official_name, official_surname, 'PRIMARY', created_at, updated_at
+			FROM hotel
+		)
+		SELECT location FROM hotel
 	`
-	// Execute the insert query with hotel details
-	_, err := r.db.ExecContext(ctx, query, hotel.ID, hotel.OfficialName, hotel.OfficialSurname, hotel.CompanyTitle, hotel.Location, hotel.CreatedAt, hotel.UpdatedAt)
-	return err // Return any error encountered
+	// Execute the insert query with hotel details and read back the canonical location
+	err = tx.QueryRowContext(ctx, query,
+		hotel.ID, hotel.OfficialName, hotel.OfficialSurname, hotel.CompanyTitle, hotel.Location, hotel.CreatedAt, hotel.UpdatedAt,
+		hotel.Latitude, hotel.Longitude,
+	).Scan(&hotel.Location)
+	if err != nil {
+		return translateError(err, "hotel") // Return any error encountered
+	}
+	if err := announce.write(ctx, tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), "hotel")
 }
 
-// Delete removes a hotel record from the database by its ID.
-func (r *HotelRepository) Delete(ctx context.Context, id uuid.UUID) error {
+// Delete removes a hotel record from the database by its ID. The message of announce is
+// written in the same transaction.
+func (r *HotelRepository) Delete(ctx context.Context, id uuid.UUID, announce Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "hotel")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
 	query := `DELETE FROM hotels WHERE id = $1`
 	// Execute the delete query using the hotel ID
-	_, err := r.db.ExecContext(ctx, query, id)
-	return err // Return any error encountered
+	result, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return translateError(err, "hotel") // Return any error encountered
+	}
+	if err := expectAffected(result, "hotel"); err != nil {
+		return err // Report a missing hotel as not found
+	}
+	if err := announce.write(ctx, tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), "hotel")
+}
+
+// Update overwrites the mutable fields of a hotel record and its primary official and refreshes
+// the hotel's canonical location and timestamps from the database. A location the new name
+// resolves to is created along with the update, and not at all if the update does not apply.
+// When expectedVersions is not nil the update only succeeds if the stored updated_at matches one
+// of them; otherwise ErrVersionConflict is returned. A NotFound error is returned if the hotel
+// does not exist. The message of announce is written in the same transaction as the update.
+func (r *HotelRepository) Update(ctx context.Context, hotel *models.Hotel, expectedVersions []time.Time, announce Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "hotel")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	// The primary official mirrors the official columns and is updated in the same statement
+	query := `
+		WITH ` + upsertLocation + `, hotel AS (
+			UPDATE hotels
+			SET official_name = $2, official_surname = $3, company_title = $4,
+			    location = (SELECT name FROM resolved_location), location_id = (SELECT id FROM resolved_location),
+			    latitude = $8, longitude = $9, updated_at = $6
+			WHERE id = $1 AND ($7::timestamptz[] IS NULL OR updated_at = ANY($7::timestamptz[]))
+			RETURNING id, official_name, official_surname, location, created_at, updated_at
+		), primary_official AS (
+			UPDATE officials o
+			SET name = hotel.official_name, surname = hotel.official_surname, updated_at = hotel.updated_at
+			FROM hotel
+			WHERE o.hotel_id = hotel.id AND o.role = 'PRIMARY'
+		)
+		SELECT location, created_at, updated_at FROM hotel
+	`
+	// Execute the update and read back the canonical location and stored timestamps
+	err = tx.QueryRowContext(ctx, query,
+		hotel.ID, hotel.OfficialName, hotel.OfficialSurname, hotel.CompanyTitle, hotel.Location, hotel.UpdatedAt, versionArray(expectedVersions),
+		hotel.Latitude, hotel.Longitude,
+	).Scan(&hotel.Location, &hotel.CreatedAt, &hotel.UpdatedAt)
+	if err == nil {
+		if err := announce.write(ctx, tx); err != nil {
+			return err
+		}
+		return translateError(tx.Commit(), "hotel")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return translateError(err, "hotel")
+	}
+
+	// No row was updated: tell a missing hotel apart from a stale version
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM hotels WHERE id = $1)`, hotel.ID).Scan(&exists); err != nil {
+		return translateError(err, "hotel")
+	}
+	if !exists {
+		return domain.NotFound("hotel not found")
+	}
+	return ErrVersionConflict
+}
+
+// versionArray converts versions to a Postgres timestamptz[] parameter, or NULL if versions is nil.
+func versionArray(versions []time.Time) interface{} {
+	if versions == nil {
+		return nil
+	}
+	formatted := make([]string, len(versions))
+	for i, v := range versions {
+		formatted[i] = v.UTC().Format(time.RFC3339Nano)
+	}
+	return pq.Array(formatted)
 }
 
 // GetByID retrieves a hotel record from the database by its ID.
 func (r *HotelRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Hotel, error) {
 	query := `
-		SELECT id, official_name, official_surname, company_title, created_at, updated_at
+		SELECT id, official_name, official_surname, company_title, location, latitude, longitude, created_at, updated_at
 		FROM hotels
 		WHERE id = $1
 	`
 	var hotel models.Hotel // Variable to hold the retrieved hotel
 	// Execute the select query and scan the result into the hotel variable
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&hotel.ID, &hotel.OfficialName, &hotel.OfficialSurname, &hotel.CompanyTitle, &hotel.CreatedAt, &hotel.UpdatedAt,
+		&hotel.ID, &hotel.OfficialName, &hotel.OfficialSurname, &hotel.CompanyTitle, &hotel.Location, &hotel.Latitude, &hotel.Longitude, &hotel.CreatedAt, &hotel.UpdatedAt,
 	)
 	if err != nil {
-		return nil, err // Return nil and the error if something went wrong
+		return nil, translateError(err, "hotel") // Return nil and the error if something went wrong
 	}
 	return &hotel, nil // Return the retrieved hotel
 }
 
+// GetByIDWithContacts retrieves a hotel record and all of its contacts in a single query.
+func (r *HotelRepository) GetByIDWithContacts(ctx context.Context, id uuid.UUID) (*models.Hotel, []*models.Contact, error) {
+	query := `
+		SELECT h.id, h.official_name, h.official_surname, h.company_title, h.location, h.latitude, h.longitude, h.created_at, h.updated_at,
+		       c.id, c.type, c.content, c.created_at, c.updated_at
+		FROM hotels h
+		LEFT JOIN contacts c ON c.hotel_id = h.id
+		WHERE h.id = $1
+		ORDER BY c.created_at, c.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, nil, translateError(err, "hotel")
+	}
+	defer rows.Close()
+
+	var (
+		hotel    *models.Hotel     // Hotel columns repeat on every row
+		contacts []*models.Contact // One contact per row, absent when the hotel has none
+	)
+	for rows.Next() {
+		var (
+			h         models.Hotel
+			contactID uuid.NullUUID
+			cType     sql.NullString
+			content   sql.NullString
+			createdAt sql.NullTime
+			updatedAt sql.NullTime
+		)
+		err := rows.Scan(
+			&h.ID, &h.OfficialName, &h.OfficialSurname, &h.CompanyTitle, &h.Location, &h.Latitude, &h.Longitude, &h.CreatedAt, &h.UpdatedAt,
+			&contactID, &cType, &content, &createdAt, &updatedAt,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if hotel == nil {
+			hotel = &h
+		}
+		if contactID.Valid {
+			contacts = append(contacts, &models.Contact{
+				ID:        contactID.UUID,
+				HotelID:   h.ID,
+				Type:      models.ContactType(cType.String),
+				Content:   content.String,
+				CreatedAt: createdAt.Time,
+				UpdatedAt: updatedAt.Time,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, translateError(err, "hotel")
+	}
+	if hotel == nil {
+		return nil, nil, domain.NotFound("hotel not found")
+	}
+	return hotel, contacts, nil
+}
+
+// locationIDByName is a subquery resolving the location name in the given parameter to
+// its location ID, matching names case- and accent-insensitively.
+func locationIDByName(param string) string {
+	return "(SELECT id FROM locations WHERE normalized_name = fold_location(" + param + "))"
+}
+
 // GetByLocation retrieves a list of hotels based on the provided location.
 func (r *HotelRepository) GetByLocation(ctx context.Context, location string) ([]*models.Hotel, error) {
 	query := `
-		SELECT id, official_name, official_surname, company_title, location, created_at, updated_at
+		SELECT id, official_name, official_surname, company_title, location, latitude, longitude, created_at, updated_at
 		FROM hotels
-		WHERE location = $1
-	`
+		WHERE location_id = ` + locationIDByName("$1")
 	rows, err := r.db.QueryContext(ctx, query, location)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, "hotel")
 	}
 	defer rows.Close()
 
 	var hotels []*models.Hotel
 	for rows.Next() {
 		var hotel models.Hotel
-		err := rows.Scan(&hotel.ID, &hotel.OfficialName, &hotel.OfficialSurname, &hotel.CompanyTitle, &hotel.Location, &hotel.CreatedAt, &hotel.UpdatedAt)
+		err := rows.Scan(&hotel.ID, &hotel.OfficialName, &hotel.OfficialSurname, &hotel.CompanyTitle, &hotel.Location, &hotel.Latitude, &hotel.Longitude, &hotel.CreatedAt, &hotel.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -80,17 +274,312 @@ func (r *HotelRepository) GetByLocation(ctx context.Context, location string) ([
 	return hotels, nil
 }
 
+// Count returns the number of hotels.
+func (r *HotelRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM hotels`).Scan(&count); err != nil {
+		return 0, translateError(err, "hotel")
+	}
+	return count, nil
+}
+
+// StatsForLocation counts the hotels of a location and their phone contacts in one statement.
+// A location without hotels yields zero counts.
+func (r *HotelRepository) StatsForLocation(ctx context.Context, location string) (*models.LocationStats, error) {
+	query := `
+		SELECT COUNT(DISTINCT h.id), COUNT(c.id) FILTER (WHERE c.type = 'PHONE')
+		FROM hotels h
+		LEFT JOIN contacts c ON c.hotel_id = h.id
+		WHERE h.location_id = ` + locationIDByName("$1")
+	stats := &models.LocationStats{Location: location}
+	err := r.db.QueryRowContext(ctx, query, location).Scan(&stats.HotelCount, &stats.PhoneCount)
+	if err != nil {
+		return nil, translateError(err, "hotel")
+	}
+	return stats, nil
+}
+
+// locationStatisticsColumns maps the sort fields of StatsByLocation to their ORDER BY expressions.
+var locationStatisticsColumns = map[string]string{
+	"location":        "location",
+	"hotel_count":     "hotel_count",
+	"phone_count":     "phone_count",
+	"email_count":     "email_count",
+	"last_updated_at": "last_updated_at",
+}
+
+// StatsByLocation computes the statistics of every location that has hotels in one grouped
+// statement. The last update is the latest updated_at of the location's hotels and contacts.
+func (r *HotelRepository) StatsByLocation(ctx context.Context, sort models.LocationStatisticsSort) ([]*models.LocationStatistics, error) {
+	if sort.Field == "" {
+		sort.Field = "location"
+	}
+	column, ok := locationStatisticsColumns[sort.Field]
+	if !ok {
+		return nil, domain.BadRequest(fmt.Sprintf("cannot sort location statistics by %q", sort.Field))
+	}
+	direction := "ASC"
+	if sort.Descending {
+		direction = "DESC"
+	}
+
+	query := `
+		SELECT h.location,
+		       COUNT(DISTINCT h.id) AS hotel_count,
+		       COUNT(c.id) FILTER (WHERE c.type = 'PHONE') AS phone_count,
+		       COUNT(c.id) FILTER (WHERE c.type = 'EMAIL') AS email_count,
+		       GREATEST(MAX(h.updated_at), MAX(c.updated_at)) AS last_updated_at
+		FROM hotels h
+		LEFT JOIN contacts c ON c.hotel_id = h.id
+		GROUP BY h.location
+		ORDER BY ` + column + ` ` + direction + ` NULLS LAST, location ASC
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, translateError(err, "hotel")
+	}
+	defer rows.Close()
+
+	stats := []*models.LocationStatistics{}
+	for rows.Next() {
+		var (
+			s           models.LocationStatistics
+			lastUpdated sql.NullTime // NULL if neither the hotels nor the contacts have a timestamp
+		)
+		if err := rows.Scan(&s.Location, &s.HotelCount, &s.PhoneCount, &s.EmailCount, &lastUpdated); err != nil {
+			return nil, translateError(err, "hotel")
+		}
+		s.LastUpdatedAt = lastUpdated.Time
+		stats = append(stats, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err, "hotel")
+	}
+	return stats, nil
+}
+
+// List retrieves a page of hotels matching the filter, ordered by (created_at, id).
+func (r *HotelRepository) List(ctx context.Context, filter models.HotelFilter) ([]*models.Hotel, error) {
+	var (
+		conditions []string      // WHERE clauses joined with AND
+		args       []interface{} // Positional query arguments
+	)
+	addArg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Location != "" {
+		conditions = append(conditions, "location_id = "+locationIDByName(addArg(filter.Location)))
+	}
+	if filter.CompanyTitlePrefix != "" {
+		conditions = append(conditions, "company_title ILIKE "+addArg(escapeLike(filter.CompanyTitlePrefix)+"%")+` ESCAPE '\'`)
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+addArg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at < "+addArg(*filter.CreatedTo))
+	}
+
+	direction, comparator := "ASC", ">"
+	if filter.Descending {
+		direction, comparator = "DESC", "<"
+	}
+	if filter.After != nil {
+		// Row comparison keeps the keyset stable for hotels sharing a creation timestamp
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s (%s, %s)", comparator, addArg(filter.After.CreatedAt), addArg(filter.After.ID)))
+	}
+
+	query := `
+		SELECT id, official_name, official_surname, company_title, location, latitude, longitude, created_at, updated_at
+		FROM hotels
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s LIMIT %s", direction, direction, addArg(filter.Limit))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err, "hotel")
+	}
+	defer rows.Close()
+
+	var hotels []*models.Hotel
+	for rows.Next() {
+		var hotel models.Hotel
+		err := rows.Scan(&hotel.ID, &hotel.OfficialName, &hotel.OfficialSurname, &hotel.CompanyTitle, &hotel.Location, &hotel.Latitude, &hotel.Longitude, &hotel.CreatedAt, &hotel.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		hotels = append(hotels, &hotel)
+	}
+	return hotels, rows.Err()
+}
+
+// earthRadiusKm is the mean radius of the earth used for great-circle distances.
+const earthRadiusKm = 6371.0088
+
+// Nearby retrieves the hotels within filter.RadiusKm of the center, closest first. Hotels
+// outside a bounding box around the center are skipped before the exact haversine distance
+// is computed; hotels without coordinates are never returned.
+func (r *HotelRepository) Nearby(ctx context.Context, filter models.NearbyFilter) ([]*models.NearbyHotel, error) {
+	minLat, maxLat, minLon, maxLon := boundingBox(filter.Latitude, filter.Longitude, filter.RadiusKm)
+	query := `
+		SELECT id, official_name, official_surname, company_title, location, latitude, longitude, created_at, updated_at, distance_km
+		FROM (
+			SELECT *, $7::float8 * 2 * asin(least(1, sqrt(
+				power(sin(radians(latitude - $1) / 2), 2) +
+				cos(radians($1)) * cos(radians(latitude)) * power(sin(radians(longitude - $2) / 2), 2)
+			))) AS distance_km
+			FROM hotels
+			WHERE latitude BETWEEN $3 AND $4 AND longitude BETWEEN $5 AND $6
+		) nearby
+		WHERE distance_km <= $8
+		ORDER BY distance_km, id
+		LIMIT $9
+	`
+	rows, err := r.db.QueryContext(ctx, query,
+		filter.Latitude, filter.Longitude, minLat, maxLat, minLon, maxLon, earthRadiusKm, filter.RadiusKm, filter.Limit,
+	)
+	if err != nil {
+		return nil, translateError(err, "hotel")
+	}
+	defer rows.Close()
+
+	hotels := []*models.NearbyHotel{} // Render no hotels as [] rather than null
+	for rows.Next() {
+		hotel := models.NearbyHotel{Hotel: &models.Hotel{}}
+		err := rows.Scan(
+			&hotel.ID, &hotel.OfficialName, &hotel.OfficialSurname, &hotel.CompanyTitle, &hotel.Location,
+			&hotel.Latitude, &hotel.Longitude, &hotel.CreatedAt, &hotel.UpdatedAt, &hotel.DistanceKm,
+		)
+		if err != nil {
+			return nil, err
+		}
+		hotels = append(hotels, &hotel)
+	}
+	return hotels, rows.Err()
+}
+
+// boundingBox returns the latitude and longitude bounds of every point within radiusKm of
+// the center. When the circle reaches a pole or crosses the antimeridian every longitude is
+// included.
+func boundingBox(lat, lon, radiusKm float64) (minLat, maxLat, minLon, maxLon float64) {
+	delta := radiusKm / earthRadiusKm // Angular radius in radians
+	minLat, maxLat = lat-delta*180/math.Pi, lat+delta*180/math.Pi
+	if minLat <= -90 || maxLat >= 90 {
+		return math.Max(minLat, -90), math.Min(maxLat, 90), -180, 180
+	}
+
+	lonDelta := math.Asin(math.Sin(delta)/math.Cos(lat*math.Pi/180)) * 180 / math.Pi
+	minLon, maxLon = lon-lonDelta, lon+lonDelta
+	if minLon < -180 || maxLon > 180 {
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLon, maxLon
+}
+
+// searchQuery is a FROM item parsing the search text in $1 with the web search syntax, e.g.
+// quoted phrases, "or" and "-" for exclusion, after folding it like the search vector.
+const searchQuery = `websearch_to_tsquery('simple'::regconfig, fold_location($1)) query`
+
+// Search matches the query text against the full-text search vector of the hotels and returns
+// the requested page of hits, best ranked first, with the location facet. Words must match
+// exactly after folding; unlike the Elasticsearch backend there is no typo tolerance and no
+// highlighting. The location filter narrows the hits but not the facet counts.
+func (r *HotelRepository) Search(ctx context.Context, search models.HotelSearchQuery) (*models.HotelSearchResult, error) {
+	// locationFilter restricts the matches to the location in the given parameter, if any
+	locationFilter := func(param string) string {
+		if search.Location == "" {
+			return "TRUE"
+		}
+		return "location_id = " + locationIDByName(param)
+	}
+
+	args := []interface{}{search.Text, search.Limit, search.Offset}
+	if search.Location != "" {
+		args = append(args, search.Location)
+	}
+
+	query := `
+		SELECT id, official_name, official_surname, company_title, location, latitude, longitude, created_at, updated_at,
+			ts_rank_cd(search_vector, query) AS score
+		FROM hotels, ` + searchQuery + `
+		WHERE search_vector @@ query AND ` + locationFilter("$4") + `
+		ORDER BY score DESC, id
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translateError(err, "hotel")
+	}
+	defer rows.Close()
+
+	result := &models.HotelSearchResult{
+		Hits:   []*models.HotelSearchHit{},
+		Facets: map[string][]models.FacetCount{"location": {}},
+	}
+	for rows.Next() {
+		hit := &models.HotelSearchHit{Hotel: &models.Hotel{}}
+		err := rows.Scan(
+			&hit.ID, &hit.OfficialName, &hit.OfficialSurname, &hit.CompanyTitle, &hit.Location,
+			&hit.Latitude, &hit.Longitude, &hit.CreatedAt, &hit.UpdatedAt, &hit.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Hits = append(result.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The facet query also counts the filtered matches, which the page may not cover
+	facetQuery := `
+		SELECT location, COUNT(*) AS count, SUM(COUNT(*) FILTER (WHERE ` + locationFilter("$3") + `)) OVER () AS total
+		FROM hotels, ` + searchQuery + `
+		WHERE search_vector @@ query
+		GROUP BY location
+		ORDER BY count DESC, location
+		LIMIT $2
+	`
+	facetArgs := []interface{}{search.Text, models.LocationFacetSize}
+	if search.Location != "" {
+		facetArgs = append(facetArgs, search.Location)
+	}
+	rows, err = r.db.QueryContext(ctx, facetQuery, facetArgs...)
+	if err != nil {
+		return nil, translateError(err, "hotel")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var facet models.FacetCount
+		if err := rows.Scan(&facet.Value, &facet.Count, &result.Total); err != nil {
+			return nil, err
+		}
+		result.Facets["location"] = append(result.Facets["location"], facet)
+	}
+	return result, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 // GetContactsByLocation retrieves a list of contacts associated with hotels based on the provided location.
 func (r *HotelRepository) GetContactsByLocation(ctx context.Context, location string) ([]*models.Contact, error) {
 	query := `
 		SELECT c.id, c.hotel_id, c.type, c.content
 		FROM contacts c
 		JOIN hotels h ON c.hotel_id = h.id
-		WHERE h.location = $1
-	`
+		WHERE h.location_id = ` + locationIDByName("$1")
 	rows, err := r.db.QueryContext(ctx, query, location)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, "hotel")
 	}
 	defer rows.Close()
 
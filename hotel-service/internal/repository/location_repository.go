@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+// ErrLocationInUse is returned when deleting a location that hotels still reference.
+var ErrLocationInUse = domain.Conflict("location is still referenced by hotels", nil)
+
+// LocationRepository is a struct that holds the database connection.
+type LocationRepository struct {
+	db *sql.DB // Database connection
+}
+
+// NewLocationRepository initializes a new LocationRepository with the provided database connection.
+func NewLocationRepository(db *sql.DB) *LocationRepository {
+	return &LocationRepository{db: db}
+}
+
+// Create inserts a new location. A Conflict error is returned if a location with the same
+// folded name already exists.
+func (r *LocationRepository) Create(ctx context.Context, location *models.Location) error {
+	query := `
+		INSERT INTO locations (id, name, country, city, district, latitude, longitude, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.ExecContext(ctx, query,
+		location.ID, location.Name, location.Country, location.City, location.District,
+		location.Latitude, location.Longitude, location.CreatedAt, location.UpdatedAt,
+	)
+	return translateError(err, "location")
+}
+
+// Update overwrites a location and renames the hotels referencing it in the same transaction,
+// bumping their updated_at. For every renamed hotel, the message of the announcement returned
+// by announce is written in the transaction as well.
+func (r *LocationRepository) Update(ctx context.Context, location *models.Location, announce func(hotel *models.Hotel) Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "location")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	query := `
+		UPDATE locations
+		SET name = $2, country = $3, city = $4, district = $5, latitude = $6, longitude = $7, updated_at = $8
+		WHERE id = $1
+		RETURNING created_at
+	`
+	err = tx.QueryRowContext(ctx, query,
+		location.ID, location.Name, location.Country, location.City, location.District,
+		location.Latitude, location.Longitude, location.UpdatedAt,
+	).Scan(&location.CreatedAt)
+	if err != nil {
+		return translateError(err, "location")
+	}
+
+	renamed, err := renameHotels(ctx, tx, location)
+	if err != nil {
+		return translateError(err, "hotel")
+	}
+	for _, hotel := range renamed {
+		if err := announce(hotel).write(ctx, tx); err != nil {
+			return err
+		}
+	}
+	return translateError(tx.Commit(), "location")
+}
+
+// renameHotels sets the location name of the hotels assigned to location and returns them.
+// The hotels are read completely before returning, so tx is free for further statements.
+func renameHotels(ctx context.Context, tx *sql.Tx, location *models.Location) ([]*models.Hotel, error) {
+	query := `
+		UPDATE hotels
+		SET location = $2, updated_at = $3
+		WHERE location_id = $1 AND location <> $2
+		RETURNING id, official_name, official_surname, company_title, location, latitude, longitude, created_at, updated_at
+	`
+	rows, err := tx.QueryContext(ctx, query, location.ID, location.Name, location.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hotels []*models.Hotel
+	for rows.Next() {
+		var hotel models.Hotel
+		err := rows.Scan(&hotel.ID, &hotel.OfficialName, &hotel.OfficialSurname, &hotel.CompanyTitle, &hotel.Location, &hotel.Latitude, &hotel.Longitude, &hotel.CreatedAt, &hotel.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		hotels = append(hotels, &hotel)
+	}
+	return hotels, rows.Err()
+}
+
+// Delete removes a location. ErrLocationInUse is returned while hotels reference it.
+func (r *LocationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM locations WHERE id = $1`, id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqForeignKeyViolation {
+		return ErrLocationInUse
+	}
+	if err != nil {
+		return translateError(err, "location")
+	}
+	return expectAffected(result, "location")
+}
+
+// GetByID retrieves a location by its ID.
+func (r *LocationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Location, error) {
+	query := `
+		SELECT id, name, country, city, district, latitude, longitude, created_at, updated_at
+		FROM locations
+		WHERE id = $1
+	`
+	location, err := scanLocation(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, translateError(err, "location")
+	}
+	return location, nil
+}
+
+// List retrieves all locations ordered by name.
+func (r *LocationRepository) List(ctx context.Context) ([]*models.Location, error) {
+	query := `
+		SELECT id, name, country, city, district, latitude, longitude, created_at, updated_at
+		FROM locations
+		ORDER BY normalized_name, id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, translateError(err, "location")
+	}
+	defer rows.Close()
+
+	locations := []*models.Location{} // Render no locations as [] rather than null
+	for rows.Next() {
+		location, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, location)
+	}
+	return locations, rows.Err()
+}
+
+// scanLocation scans the columns selected by the location queries into a Location.
+func scanLocation(row rowScanner) (*models.Location, error) {
+	var location models.Location
+	err := row.Scan(
+		&location.ID, &location.Name, &location.Country, &location.City, &location.District,
+		&location.Latitude, &location.Longitude, &location.CreatedAt, &location.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+// OfficialRepository is a struct that holds the database connection.
+type OfficialRepository struct {
+	db *sql.DB // Database connection
+}
+
+// NewOfficialRepository initializes a new OfficialRepository with the provided database connection.
+func NewOfficialRepository(db *sql.DB) *OfficialRepository {
+	return &OfficialRepository{db: db}
+}
+
+// Create inserts a new official into the database. The message of announce is written in the
+// same transaction.
+func (r *OfficialRepository) Create(ctx context.Context, official *models.Official, announce Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "official")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	query := `
+		INSERT INTO officials (id, hotel_id, name, surname, role, title, active_from, active_to, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	// Execute the insert query with the official's details.
+	_, err = tx.ExecContext(ctx, query,
+		official.ID, official.HotelID, official.Name, official.Surname, official.Role, official.Title,
+		official.ActiveFrom, official.ActiveTo, official.CreatedAt, official.UpdatedAt,
+	)
+	if err != nil {
+		return translateError(err, "official")
+	}
+	if err := announce.write(ctx, tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), "official")
+}
+
+// Delete removes an official of the given hotel from the database. The message of announce is
+// written in the same transaction.
+func (r *OfficialRepository) Delete(ctx context.Context, hotelID, id uuid.UUID, announce Announcement) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "official")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	query := `DELETE FROM officials WHERE id = $1 AND hotel_id = $2`
+	result, err := tx.ExecContext(ctx, query, id, hotelID)
+	if err != nil {
+		return translateError(err, "official")
+	}
+	if err := expectAffected(result, "official"); err != nil {
+		return err // Report a missing official as not found
+	}
+	if err := announce.write(ctx, tx); err != nil {
+		return err
+	}
+	return translateError(tx.Commit(), "official")
+}
+
+// GetByID retrieves a single official by its ID, provided it belongs to the given hotel.
+func (r *OfficialRepository) GetByID(ctx context.Context, hotelID, id uuid.UUID) (*models.Official, error) {
+	query := `
+		SELECT id, hotel_id, name, surname, role, title, active_from, active_to, created_at, updated_at
+		FROM officials
+		WHERE id = $1 AND hotel_id = $2
+	`
+	official, err := scanOfficial(r.db.QueryRowContext(ctx, query, id, hotelID))
+	if err != nil {
+		return nil, translateError(err, "official")
+	}
+	return official, nil
+}
+
+// GetByHotelID retrieves all officials of a hotel, the primary official first.
+func (r *OfficialRepository) GetByHotelID(ctx context.Context, hotelID uuid.UUID) ([]*models.Official, error) {
+	query := `
+		SELECT id, hotel_id, name, surname, role, title, active_from, active_to, created_at, updated_at
+		FROM officials
+		WHERE hotel_id = $1
+		ORDER BY role = 'PRIMARY' DESC, created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, hotelID)
+	if err != nil {
+		return nil, translateError(err, "official")
+	}
+	defer rows.Close()
+
+	officials := []*models.Official{} // Render no officials as [] rather than null
+	for rows.Next() {
+		official, err := scanOfficial(rows)
+		if err != nil {
+			return nil, err
+		}
+		officials = append(officials, official)
+	}
+	return officials, rows.Err()
+}
+
+// GetByHotelIDs retrieves the officials of several hotels in a single query, grouped by hotel ID
+// with each hotel's primary official first.
+func (r *OfficialRepository) GetByHotelIDs(ctx context.Context, hotelIDs []uuid.UUID) (map[uuid.UUID][]*models.Official, error) {
+	query := `
+		SELECT id, hotel_id, name, surname, role, title, active_from, active_to, created_at, updated_at
+		FROM officials
+		WHERE hotel_id = ANY($1::uuid[])
+		ORDER BY role = 'PRIMARY' DESC, created_at, id
+	`
+	ids := make([]string, len(hotelIDs))
+	for i, id := range hotelIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, pq.StringArray(ids))
+	if err != nil {
+		return nil, translateError(err, "official")
+	}
+	defer rows.Close()
+
+	officials := make(map[uuid.UUID][]*models.Official)
+	for rows.Next() {
+		official, err := scanOfficial(rows)
+		if err != nil {
+			return nil, err
+		}
+		officials[official.HotelID] = append(officials[official.HotelID], official)
+	}
+	return officials, rows.Err()
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOfficial scans the columns selected by the official queries into an Official.
+func scanOfficial(row rowScanner) (*models.Official, error) {
+	var official models.Official
+	err := row.Scan(
+		&official.ID, &official.HotelID, &official.Name, &official.Surname, &official.Role, &official.Title,
+		&official.ActiveFrom, &official.ActiveTo, &official.CreatedAt, &official.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &official, nil
+}
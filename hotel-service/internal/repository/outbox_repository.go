@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/tracing"
+)
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// OutboxRepository is a struct that holds the database connection.
+type OutboxRepository struct {
+	db *sql.DB // Database connection
+}
+
+// NewOutboxRepository initializes a new OutboxRepository with the provided database connection.
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// insertOutboxMessage writes a message to the outbox using the given connection or transaction.
+// Unless the message has a trace context already, the trace context of ctx is stored with it so
+// the dispatcher continues the trace when it publishes the message.
+func insertOutboxMessage(ctx context.Context, exec execer, msg *models.OutboxMessage) error {
+	if msg.TraceContext == nil {
+		msg.TraceContext = tracing.Inject(ctx)
+	}
+	var traceContext []byte // NULL outside of a trace
+	if msg.TraceContext != nil {
+		var err error
+		if traceContext, err = json.Marshal(msg.TraceContext); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO outbox (id, exchange, destination, correlation_id, payload, trace_context, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err := exec.ExecContext(ctx, query, msg.ID, msg.Exchange, msg.Destination, msg.CorrelationID, msg.Payload, traceContext, msg.CreatedAt)
+	return err
+}
+
+// Announcement builds the outbox message announcing a change. Repositories call it within the
+// transaction of the change once the change is applied, so the message describes the stored
+// state, and write the message in the same transaction: it is published if and only if the
+// change is committed.
+type Announcement func(ctx context.Context) (*models.OutboxMessage, error)
+
+// write adds the message of the announcement to the outbox. A nil announcement writes nothing.
+func (a Announcement) write(ctx context.Context, exec execer) error {
+	if a == nil {
+		return nil
+	}
+	msg, err := a(ctx)
+	if err != nil {
+		return err
+	}
+	return translateError(insertOutboxMessage(ctx, exec, msg), "outbox message")
+}
+
+// Add writes a message to the outbox outside of any other transaction.
+func (r *OutboxRepository) Add(ctx context.Context, msg *models.OutboxMessage) error {
+	return translateError(insertOutboxMessage(ctx, r.db, msg), "outbox message")
+}
+
+// Claim leases up to limit messages that are due for publishing. A claimed message is hidden
+// from other dispatchers for the lease duration, after which it becomes claimable again
+// unless it was marked as sent.
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxMessage, error) {
+	query := `
+		UPDATE outbox
+		SET locked_until = now() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM outbox
+			WHERE sent_at IS NULL
+			  AND next_attempt_at <= now()
+			  AND (locked_until IS NULL OR locked_until < now())
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, exchange, destination, correlation_id, payload, trace_context, attempts, created_at
+	`
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, translateError(err, "outbox message")
+	}
+	defer rows.Close()
+
+	var messages []*models.OutboxMessage
+	for rows.Next() {
+		var (
+			msg          models.OutboxMessage
+			traceContext []byte
+		)
+		if err := rows.Scan(&msg.ID, &msg.Exchange, &msg.Destination, &msg.CorrelationID, &msg.Payload, &traceContext, &msg.Attempts, &msg.CreatedAt); err != nil {
+			return nil, err
+		}
+		if traceContext != nil {
+			if err := json.Unmarshal(traceContext, &msg.TraceContext); err != nil {
+				return nil, fmt.Errorf("failed to decode trace context of outbox message %s: %w", msg.ID, err)
+			}
+		}
+		messages = append(messages, &msg)
+	}
+	// RETURNING does not preserve the subquery order
+	sort.Slice(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+	return messages, rows.Err()
+}
+
+// MarkSent records that a message was published.
+func (r *OutboxRepository) MarkSent(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE outbox SET sent_at = now(), locked_until = NULL WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id)
+	return translateError(err, "outbox message")
+}
+
+// MarkFailed records a failed publish attempt and schedules the next one.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uuid.UUID, cause string, nextAttempt time.Time) error {
+	query := `
+		UPDATE outbox
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3, locked_until = NULL
+		WHERE id = $1
+	`
+	_, err := r.db.ExecContext(ctx, query, id, cause, nextAttempt)
+	return translateError(err, "outbox message")
+}
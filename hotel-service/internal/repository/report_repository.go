@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+// ReportRepository is a struct that holds the database connection.
+type ReportRepository struct {
+	db *sql.DB // Database connection
+}
+
+// NewReportRepository initializes a new ReportRepository with the provided database connection.
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// Create inserts a report request and its outbox messages in a single transaction,
+// so the request is persisted if and only if its messages will be published.
+func (r *ReportRepository) Create(ctx context.Context, request *models.ReportRequest, messages ...*models.OutboxMessage) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err, "report request")
+	}
+	defer tx.Rollback() // No-op once the transaction is committed
+
+	query := `
+		INSERT INTO report_requests (id, location, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.ExecContext(ctx, query, request.ID, request.Location, request.Status, request.CreatedAt, request.UpdatedAt); err != nil {
+		return translateError(err, "report request")
+	}
+	for _, msg := range messages {
+		if err := insertOutboxMessage(ctx, tx, msg); err != nil {
+			return translateError(err, "outbox message")
+		}
+	}
+	return translateError(tx.Commit(), "report request")
+}
+
+// GetByID retrieves a report request by its ID.
+func (r *ReportRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.ReportRequest, error) {
+	query := `
+		SELECT id, location, status, created_at, updated_at
+		FROM report_requests
+		WHERE id = $1
+	`
+	request, err := scanReportRequest(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, translateError(err, "report request")
+	}
+	return request, nil
+}
+
+// List retrieves the most recent report requests matching the filter, newest first.
+func (r *ReportRepository) List(ctx context.Context, filter models.ReportFilter) ([]*models.ReportRequest, error) {
+	query := `
+		SELECT id, location, status, created_at, updated_at
+		FROM report_requests
+		WHERE ($1::text = '' OR status = $1)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, filter.Status, filter.Limit)
+	if err != nil {
+		return nil, translateError(err, "report request")
+	}
+	defer rows.Close()
+
+	requests := []*models.ReportRequest{} // Render no requests as [] rather than null
+	for rows.Next() {
+		request, err := scanReportRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, translateError(rows.Err(), "report request")
+}
+
+// UpdateStatus moves a report request to the given status if it is currently in one of the
+// statuses that may precede it. It reports whether the status changed; a request that has
+// already moved past the status is left untouched.
+func (r *ReportRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.ReportStatus, updatedAt time.Time) (bool, error) {
+	preceding := pq.StringArray{}
+	for _, s := range status.PrecedingStatuses() {
+		preceding = append(preceding, string(s))
+	}
+
+	query := `
+		UPDATE report_requests
+		SET status = $2, updated_at = $3
+		WHERE id = $1 AND status = ANY($4)
+	`
+	result, err := r.db.ExecContext(ctx, query, id, status, updatedAt, preceding)
+	if err != nil {
+		return false, translateError(err, "report request")
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return affected > 0, translateError(err, "report request")
+	}
+
+	// Distinguish an unknown request from one that is already past the status
+	var exists bool
+	err = r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM report_requests WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return false, translateError(err, "report request")
+	}
+	if !exists {
+		return false, domain.NotFound("report request not found")
+	}
+	return false, nil
+}
+
+// scanReportRequest scans a report request row.
+func scanReportRequest(row rowScanner) (*models.ReportRequest, error) {
+	var request models.ReportRequest
+	err := row.Scan(&request.ID, &request.Location, &request.Status, &request.CreatedAt, &request.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
@@ -0,0 +1,272 @@
+// Package search maintains the Elasticsearch index used for full-text hotel search.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/olivere/elastic/v7"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+// HotelsAlias is the alias searches and writes go through. It points at one physical index
+// named after it, which Rebuild replaces without interrupting searches.
+const HotelsAlias = "hotels"
+
+// ErrIndexUnavailable is returned when the index is known to be unreachable.
+var ErrIndexUnavailable = domain.Unavailable("search index unavailable", nil)
+
+// bulkBatchSize is the number of documents sent per bulk request while rebuilding.
+const bulkBatchSize = 500
+
+// searchFields are the fields matched by a search, with their boosts.
+var searchFields = []string{"company_title^3", "location^2", "official_name", "official_surname", "officials", "contacts"}
+
+// highlightFields are the fields matched fragments are returned for.
+var highlightFields = []string{"company_title", "location", "official_name", "official_surname", "officials"}
+
+// indexBody holds the settings and mappings of a hotel index. Text is folded to ASCII before it
+// is lower-cased, so "İstanbul", "Istanbul" and "istanbul" match each other.
+const indexBody = `{
+	"settings": {
+		"analysis": {
+			"analyzer": {
+				"folding": {"tokenizer": "standard", "filter": ["asciifolding", "lowercase"]}
+			}
+		}
+	},
+	"mappings": {
+		"dynamic": false,
+		"properties": {
+			"id":               {"type": "keyword"},
+			"company_title":    {"type": "text", "analyzer": "folding"},
+			"official_name":    {"type": "text", "analyzer": "folding"},
+			"official_surname": {"type": "text", "analyzer": "folding"},
+			"officials":        {"type": "text", "analyzer": "folding"},
+			"location":         {"type": "text", "analyzer": "folding", "fields": {"keyword": {"type": "keyword"}}},
+			"contacts":         {"type": "text", "analyzer": "folding"},
+			"latitude":         {"type": "double"},
+			"longitude":        {"type": "double"},
+			"created_at":       {"type": "date"},
+			"updated_at":       {"type": "date"}
+		}
+	}
+}`
+
+// Document is the indexed representation of a hotel: the hotel as returned by the REST API
+// plus the content of its contacts and the names of its officials.
+type Document struct {
+	*models.Hotel
+	Contacts  []string `json:"contacts,omitempty"`  // Contact content, e.g. email addresses and websites
+	Officials []string `json:"officials,omitempty"` // Full names of all officials, the primary official included
+}
+
+// NewDocument builds the document of a hotel, its contacts and its officials.
+func NewDocument(hotel *models.Hotel, contacts []*models.Contact, officials []*models.Official) *Document {
+	doc := &Document{Hotel: hotel}
+	for _, c := range contacts {
+		doc.Contacts = append(doc.Contacts, c.Content)
+	}
+	for _, o := range officials {
+		doc.Officials = append(doc.Officials, o.Name+" "+o.Surname)
+	}
+	return doc
+}
+
+// Index is the hotel search index.
+type Index struct {
+	client *elastic.Client
+	alias  string
+}
+
+// NewIndex creates an Index accessed through HotelsAlias.
+func NewIndex(client *elastic.Client) *Index {
+	return &Index{client: client, alias: HotelsAlias}
+}
+
+// Ensure creates the index and its alias unless the alias already exists.
+func (i *Index) Ensure(ctx context.Context) error {
+	indices, err := i.aliasedIndices(ctx)
+	if err != nil || len(indices) > 0 {
+		return err
+	}
+	name, err := i.create(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = i.client.Alias().Add(name, i.alias).Do(ctx)
+	return translateError(err)
+}
+
+// Put indexes a document, replacing the previous version of the hotel.
+func (i *Index) Put(ctx context.Context, doc *Document) error {
+	_, err := i.client.Index().Index(i.alias).Id(doc.ID.String()).BodyJson(doc).Do(ctx)
+	return translateError(err)
+}
+
+// Delete removes a hotel from the index. Deleting a hotel that is not indexed succeeds.
+func (i *Index) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := i.client.Delete().Index(i.alias).Id(id.String()).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil
+	}
+	return translateError(err)
+}
+
+// Search matches the query text fuzzily and returns the requested page of hits, highlighted,
+// with the location facet. The location filter narrows the hits but not the facet counts, so
+// clients can show every location the text matched in.
+func (i *Index) Search(ctx context.Context, query models.HotelSearchQuery) (*models.HotelSearchResult, error) {
+	search := i.client.Search(i.alias).
+		Query(elastic.NewMultiMatchQuery(query.Text, searchFields...).Fuzziness("AUTO")).
+		Aggregation("location", elastic.NewTermsAggregation().Field("location.keyword").Size(models.LocationFacetSize)).
+		Highlight(elastic.NewHighlight().Fields(highlightFieldsOf(highlightFields)...).PreTags("<em>").PostTags("</em>")).
+		From(query.Offset).
+		Size(query.Limit).
+		TrackTotalHits(true)
+	if query.Location != "" {
+		search = search.PostFilter(elastic.NewTermQuery("location.keyword", query.Location))
+	}
+
+	res, err := search.Do(ctx)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	result := &models.HotelSearchResult{
+		Hits:   []*models.HotelSearchHit{},
+		Facets: map[string][]models.FacetCount{"location": {}},
+	}
+	if res.Hits != nil {
+		if res.Hits.TotalHits != nil {
+			result.Total = res.Hits.TotalHits.Value
+		}
+		for _, h := range res.Hits.Hits {
+			var doc Document
+			if err := json.Unmarshal(h.Source, &doc); err != nil {
+				return nil, fmt.Errorf("failed to decode search hit %s: %w", h.Id, err)
+			}
+			hit := &models.HotelSearchHit{Hotel: doc.Hotel, Highlights: h.Highlight}
+			if h.Score != nil {
+				hit.Score = *h.Score
+			}
+			result.Hits = append(result.Hits, hit)
+		}
+	}
+	if terms, found := res.Aggregations.Terms("location"); found {
+		for _, bucket := range terms.Buckets {
+			result.Facets["location"] = append(result.Facets["location"], models.FacetCount{
+				Value: fmt.Sprint(bucket.Key),
+				Count: bucket.DocCount,
+			})
+		}
+	}
+	return result, nil
+}
+
+// Rebuild indexes the documents produced by load into a new physical index, then moves the
+// alias to it and deletes the indices it pointed to before. Searches keep using the old index
+// until the switch. Writes made while the rebuild runs go to the old index, so hotels changed
+// during a rebuild may need another write or rebuild to be current. It returns the number of
+// documents indexed.
+func (i *Index) Rebuild(ctx context.Context, load func(yield func(*Document) error) error) (int, error) {
+	name, err := i.create(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	bulk := i.client.Bulk().Index(name)
+	flush := func() error {
+		if bulk.NumberOfActions() == 0 {
+			return nil
+		}
+		res, err := bulk.Do(ctx)
+		if err != nil {
+			return translateError(err)
+		}
+		if failed := res.Failed(); len(failed) > 0 {
+			return fmt.Errorf("failed to index hotel %s: %v", failed[0].Id, failed[0].Error.Reason)
+		}
+		return nil
+	}
+	err = load(func(doc *Document) error {
+		bulk.Add(elastic.NewBulkIndexRequest().Id(doc.ID.String()).Doc(doc))
+		count++
+		if bulk.NumberOfActions() >= bulkBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		i.client.DeleteIndex(name).Do(ctx) // Leave the current index in place
+		return 0, err
+	}
+
+	old, err := i.aliasedIndices(ctx)
+	if err != nil {
+		return 0, err
+	}
+	swap := i.client.Alias().Add(name, i.alias)
+	for _, index := range old {
+		swap = swap.Remove(index, i.alias)
+	}
+	if _, err := swap.Do(ctx); err != nil {
+		return 0, translateError(err)
+	}
+	if len(old) > 0 {
+		if _, err := i.client.DeleteIndex(old...).Do(ctx); err != nil {
+			return count, translateError(err)
+		}
+	}
+	return count, nil
+}
+
+// create creates a new physical index with the hotel mappings and returns its name.
+func (i *Index) create(ctx context.Context) (string, error) {
+	name := fmt.Sprintf("%s-%d", i.alias, time.Now().UnixNano())
+	_, err := i.client.CreateIndex(name).BodyString(indexBody).Do(ctx)
+	if err != nil {
+		return "", translateError(err)
+	}
+	return name, nil
+}
+
+// aliasedIndices returns the physical indices the alias points to.
+func (i *Index) aliasedIndices(ctx context.Context) ([]string, error) {
+	res, err := i.client.Aliases().Alias(i.alias).Do(ctx)
+	if elastic.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return res.IndicesByAlias(i.alias), nil
+}
+
+// highlightFieldsOf returns highlighter settings for the named fields.
+func highlightFieldsOf(names []string) []*elastic.HighlighterField {
+	fields := make([]*elastic.HighlighterField, len(names))
+	for i, name := range names {
+		fields[i] = elastic.NewHighlighterField(name)
+	}
+	return fields
+}
+
+// translateError reports an unreachable or overloaded cluster as unavailable.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if elastic.IsConnErr(err) || elastic.IsTimeout(err) || elastic.IsStatusCode(err, 429) || elastic.IsStatusCode(err, 503) {
+		return domain.Unavailable("search index unavailable", err)
+	}
+	return err
+}
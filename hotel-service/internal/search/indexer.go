@@ -0,0 +1,148 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+// HotelLoader reads a hotel and its contacts, e.g. repository.HotelRepository.
+type HotelLoader interface {
+	GetByIDWithContacts(ctx context.Context, id uuid.UUID) (*models.Hotel, []*models.Contact, error)
+}
+
+// OfficialLoader reads the officials of a hotel, e.g. repository.OfficialRepository.
+type OfficialLoader interface {
+	GetByHotelID(ctx context.Context, hotelID uuid.UUID) ([]*models.Official, error)
+}
+
+// maxPending is the largest number of hotels whose documents are held back while the index is
+// unavailable. Changes beyond it are lost until the next rebuild.
+const maxPending = 10000
+
+// Indexer is an events.Publisher that keeps the index in sync with hotel, contact and official
+// changes. The document of a changed hotel is rebuilt from the database rather than from the
+// event, so it includes the hotel's current contacts and officials. While the index is
+// unavailable the changed hotels are remembered instead, and Flush brings their documents up to
+// date once it is back. The event of a held change counts as handled, so the held changes only
+// live in memory: those not flushed before the process exits are lost until the next rebuild.
+type Indexer struct {
+	index     *Index         // Index to update
+	hotels    HotelLoader    // Source of the indexed documents
+	officials OfficialLoader // Source of the officials in the documents
+	available func() bool    // Reports whether the index is reachable; nil if always assumed
+
+	mu         sync.Mutex
+	pending    map[uuid.UUID]uint64 // Hotels changed while the index was unavailable, by generation
+	generation uint64               // Incremented by each held change
+}
+
+// NewIndexer creates an Indexer updating index from hotels and officials. If available reports
+// the index unreachable, changes are held back without contacting it; available may be nil.
+func NewIndexer(index *Index, hotels HotelLoader, officials OfficialLoader, available func() bool) *Indexer {
+	return &Indexer{index: index, hotels: hotels, officials: officials, available: available, pending: make(map[uuid.UUID]uint64)}
+}
+
+// hotelReference holds the fields that identify the hotel an event is about.
+type hotelReference struct {
+	ID      uuid.UUID `json:"id"`       // Hotel ID for hotel events, contact or official ID otherwise
+	HotelID uuid.UUID `json:"hotel_id"` // Hotel of a contact or official event
+}
+
+// Publish updates the index for hotel, contact and official events and ignores other events.
+func (i *Indexer) Publish(ctx context.Context, event *events.Envelope) error {
+	var ref hotelReference
+	if err := json.Unmarshal(event.Payload, &ref); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %w", event.Type, err)
+	}
+
+	var id uuid.UUID
+	switch event.Type {
+	case events.HotelCreated.Name, events.HotelUpdated.Name, events.HotelDeleted.Name:
+		id = ref.ID
+	case events.ContactAdded.Name, events.ContactUpdated.Name, events.ContactRemoved.Name, events.OfficialAdded.Name, events.OfficialRemoved.Name:
+		id = ref.HotelID
+	default:
+		return nil
+	}
+
+	var err error
+	if i.available != nil && !i.available() {
+		err = ErrIndexUnavailable
+	} else if event.Type == events.HotelDeleted.Name {
+		err = i.index.Delete(ctx, id)
+	} else {
+		err = i.reindex(ctx, id)
+	}
+	if domain.KindOf(err) == domain.KindUnavailable {
+		return i.hold(id, err)
+	}
+	return err
+}
+
+// Flush updates the documents of the hotels changed while the index was unavailable. It stops
+// at the first hotel that cannot be updated, which stays pending along with the rest. A hotel
+// held again while its document is updated stays pending too, as the update may predate the
+// change.
+func (i *Indexer) Flush(ctx context.Context) error {
+	i.mu.Lock()
+	generations := make(map[uuid.UUID]uint64, len(i.pending))
+	for id, generation := range i.pending {
+		generations[id] = generation
+	}
+	i.mu.Unlock()
+
+	for id, generation := range generations {
+		if err := i.reindex(ctx, id); err != nil {
+			return err
+		}
+		i.mu.Lock()
+		if i.pending[id] == generation {
+			delete(i.pending, id)
+		}
+		i.mu.Unlock()
+	}
+	return nil
+}
+
+// Pending returns the number of hotels waiting for Flush.
+func (i *Indexer) Pending() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return len(i.pending)
+}
+
+// hold remembers a hotel whose document could not be updated because of err. It returns
+// err if too many hotels are pending already.
+func (i *Indexer) hold(id uuid.UUID, err error) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, ok := i.pending[id]; !ok && len(i.pending) >= maxPending {
+		return fmt.Errorf("too many pending search index updates, rebuild the index: %w", err)
+	}
+	i.generation++
+	i.pending[id] = i.generation
+	return nil
+}
+
+// reindex replaces the document of a hotel, or removes it if the hotel no longer exists.
+func (i *Indexer) reindex(ctx context.Context, id uuid.UUID) error {
+	hotel, contacts, err := i.hotels.GetByIDWithContacts(ctx, id)
+	if domain.IsNotFound(err) {
+		return i.index.Delete(ctx, id) // Deleted since the event was emitted
+	}
+	if err != nil {
+		return err
+	}
+	officials, err := i.officials.GetByHotelID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return i.index.Put(ctx, NewDocument(hotel, contacts, officials))
+}
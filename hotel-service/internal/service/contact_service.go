@@ -2,9 +2,10 @@ package service
 
 import (
 	"context"
-	"time"
+	"encoding/json"
 
 	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/events"
 	"github.com/tfgoztok/hotel-service/internal/models"
 	"github.com/tfgoztok/hotel-service/internal/repository"
 )
@@ -16,23 +17,73 @@ type ContactService struct {
 
 // NewContactService creates a new instance of ContactService.
 func NewContactService(repo *repository.ContactRepository) *ContactService {
-	return &ContactService{repo: repo} // Initialize ContactService with the provided repository
+	return &ContactService{repo: repo}
 }
 
 // AddContact adds a new contact to the repository.
 func (s *ContactService) AddContact(ctx context.Context, contact *models.Contact) error {
-	contact.ID = uuid.New()            // Generate a new unique ID for the contact
-	contact.CreatedAt = time.Now()     // Set the creation timestamp
-	contact.UpdatedAt = time.Now()     // Set the updated timestamp
-	return s.repo.Create(ctx, contact) // Save the contact to the repository
+	if err := validateContact(contact); err != nil {
+		return err // Reject invalid input before touching the database
+	}
+	contact.ID = uuid.New()               // Generate a new unique ID for the contact
+	contact.CreatedAt = now()             // Set the creation timestamp
+	contact.UpdatedAt = contact.CreatedAt // Set the updated timestamp
+	return s.repo.Create(ctx, contact, announce(events.ContactAdded, contact))
 }
 
-// DeleteContact removes a contact from the repository by its ID.
-func (s *ContactService) DeleteContact(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id) // Call the repository to delete the contact
+// GetContact retrieves a single contact of a hotel.
+func (s *ContactService) GetContact(ctx context.Context, hotelID, id uuid.UUID) (*models.Contact, error) {
+	return s.repo.GetByID(ctx, hotelID, id)
 }
 
-// GetContactsByHotelID retrieves all contacts associated with a specific hotel ID.
-func (s *ContactService) GetContactsByHotelID(ctx context.Context, hotelID uuid.UUID) ([]*models.Contact, error) {
-	return s.repo.GetByHotelID(ctx, hotelID) // Fetch contacts from the repository by hotel ID
+// PatchContact applies a JSON merge patch to a contact of a hotel and returns the updated contact.
+func (s *ContactService) PatchContact(ctx context.Context, hotelID, id uuid.UUID, patch []byte) (*models.Contact, error) {
+	current, err := s.repo.GetByID(ctx, hotelID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := applyMergePatch(doc, patch)
+	if err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	var contact models.Contact
+	if err := json.Unmarshal(merged, &contact); err != nil {
+		return nil, ErrInvalidPatch
+	}
+	contact.ID = current.ID // Identity, ownership and creation time are not patchable
+	contact.HotelID = current.HotelID
+	contact.CreatedAt = current.CreatedAt
+
+	if err := validateContact(&contact); err != nil {
+		return nil, err // Reject invalid input before touching the database
+	}
+	contact.UpdatedAt = now()
+	if err := s.repo.Update(ctx, &contact, announce(events.ContactUpdated, &contact)); err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// DeleteContact removes a contact of a hotel from the repository by its ID.
+func (s *ContactService) DeleteContact(ctx context.Context, hotelID, id uuid.UUID) error {
+	return s.repo.Delete(ctx, hotelID, id, announce(events.ContactRemoved, events.ContactRemovedPayload{ID: id, HotelID: hotelID}))
+}
+
+// GetContactsByHotelID retrieves the contacts associated with a specific hotel ID,
+// optionally restricted to the given contact types.
+func (s *ContactService) GetContactsByHotelID(ctx context.Context, hotelID uuid.UUID, types ...models.ContactType) ([]*models.Contact, error) {
+	contacts, err := s.repo.GetByHotelID(ctx, hotelID, types...) // Fetch contacts from the repository by hotel ID
+	if err != nil {
+		return nil, err
+	}
+	if contacts == nil {
+		contacts = []*models.Contact{} // Render no contacts as [] rather than null
+	}
+	return contacts, nil
 }
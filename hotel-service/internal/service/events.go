@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/messaging"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+)
+
+// announce returns the announcement of a change with an event of the given type for payload.
+// The repositories write the event to the outbox in the transaction of the change, so it is
+// published if and only if the change is committed. payload is encoded once the change is
+// applied, so it describes the stored state.
+func announce(eventType events.Type, payload interface{}) repository.Announcement {
+	return func(ctx context.Context) (*models.OutboxMessage, error) {
+		event, err := events.New(ctx, eventType, payload)
+		if err != nil {
+			return nil, err
+		}
+		return messaging.EventMessage(event)
+	}
+}
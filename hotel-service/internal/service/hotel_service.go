@@ -2,53 +2,253 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/events"
 	"github.com/tfgoztok/hotel-service/internal/models"
 	"github.com/tfgoztok/hotel-service/internal/repository"
 )
 
+const (
+	DefaultPageSize = 20  // Page size used when the caller does not request one
+	MaxPageSize     = 100 // Upper bound on the page size a caller may request
+
+	MaxNearbyRadiusKm = 500 // Largest radius a proximity search may cover
+)
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+	ErrInvalidCursor = domain.BadRequest("invalid cursor")
+	// ErrCursorSortMismatch is returned when a cursor is used with another sort than the one
+	// of the page it was returned with.
+	ErrCursorSortMismatch = domain.BadRequest("cursor does not match the requested sort")
+	// ErrInvalidPatch is returned when a merge patch is not a valid JSON document.
+	ErrInvalidPatch = domain.BadRequest("invalid merge patch")
+)
+
 // HotelService provides methods to manage hotels.
 type HotelService struct {
-	repo *repository.HotelRepository // Repository for hotel data
+	repo      *repository.HotelRepository    // Repository for hotel data
+	officials *repository.OfficialRepository // Repository for the officials included in hotel details
 }
 
 // NewHotelService creates a new instance of HotelService.
-func NewHotelService(repo *repository.HotelRepository) *HotelService {
-	return &HotelService{repo: repo} // Initialize HotelService with the provided repository
+func NewHotelService(repo *repository.HotelRepository, officials *repository.OfficialRepository) *HotelService {
+	return &HotelService{repo: repo, officials: officials}
 }
 
 // CreateHotel creates a new hotel record in the repository.
 func (s *HotelService) CreateHotel(ctx context.Context, hotel *models.Hotel) error {
-	hotel.ID = uuid.New()            // Generate a new unique ID for the hotel
-	hotel.CreatedAt = time.Now()     // Set the creation timestamp
-	hotel.UpdatedAt = time.Now()     // Set the updated timestamp
-	return s.repo.Create(ctx, hotel) // Save the hotel to the repository
+	if err := validateHotel(hotel); err != nil {
+		return err // Reject invalid input before touching the database
+	}
+	hotel.ID = uuid.New()             // Generate a new unique ID for the hotel
+	hotel.CreatedAt = now()           // Set the creation timestamp
+	hotel.UpdatedAt = hotel.CreatedAt // Set the updated timestamp
+	return s.repo.Create(ctx, hotel, announce(events.HotelCreated, hotel))
+}
+
+// UpdateHotel replaces the mutable fields of an existing hotel.
+// If versions is not nil the update is rejected with repository.ErrVersionConflict
+// unless the hotel's updated_at still equals one of them.
+func (s *HotelService) UpdateHotel(ctx context.Context, hotel *models.Hotel, versions []time.Time) error {
+	if err := validateHotel(hotel); err != nil {
+		return err // Reject invalid input before touching the database
+	}
+	hotel.UpdatedAt = now() // Bump the version of the hotel
+	// Persist the changes, refreshing created_at from the database
+	return s.repo.Update(ctx, hotel, versions, announce(events.HotelUpdated, hotel))
+}
+
+// PatchHotel applies a JSON merge patch to a hotel and returns the updated hotel.
+// Without explicit versions the update is guarded by the version that was patched,
+// so a concurrent write between reading and updating is still detected.
+func (s *HotelService) PatchHotel(ctx context.Context, id uuid.UUID, patch []byte, versions []time.Time) (*models.Hotel, error) {
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []time.Time{current.UpdatedAt}
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := applyMergePatch(doc, patch)
+	if err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	var hotel models.Hotel
+	if err := json.Unmarshal(merged, &hotel); err != nil {
+		return nil, ErrInvalidPatch
+	}
+	hotel.ID = current.ID // Identity and creation time are not patchable
+	hotel.CreatedAt = current.CreatedAt
+
+	if err := s.UpdateHotel(ctx, &hotel, versions); err != nil {
+		return nil, err
+	}
+	return &hotel, nil
 }
 
 // DeleteHotel removes a hotel record from the repository by its ID.
 func (s *HotelService) DeleteHotel(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id) // Call the repository to delete the hotel
+	return s.repo.Delete(ctx, id, announce(events.HotelDeleted, events.HotelDeletedPayload{ID: id}))
 }
 
-// GetHotelDetails retrieves hotel details by its ID.
-func (s *HotelService) GetHotelDetails(ctx context.Context, id uuid.UUID) (*models.Hotel, error) {
-	return s.repo.GetByID(ctx, id) // Fetch the hotel details from the repository
+// GetHotelDetails retrieves hotel details by its ID along with the requested expansions.
+func (s *HotelService) GetHotelDetails(ctx context.Context, id uuid.UUID, include models.HotelIncludes) (*models.HotelDetails, error) {
+	var details models.HotelDetails
+	if include.Contacts {
+		// Fetch the hotel and its contacts in a single round trip
+		hotel, contacts, err := s.repo.GetByIDWithContacts(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		details.Hotel = hotel
+		details.Contacts = make(map[models.ContactType][]*models.Contact)
+		for _, contact := range contacts {
+			details.Contacts[contact.Type] = append(details.Contacts[contact.Type], contact)
+		}
+	} else {
+		hotel, err := s.repo.GetByID(ctx, id) // Fetch the hotel details from the repository
+		if err != nil {
+			return nil, err
+		}
+		details.Hotel = hotel
+	}
+
+	if include.Officials {
+		officials, err := s.officials.GetByHotelID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		details.Officials = officials
+	}
+	return &details, nil
 }
 
-// ListOfficials retrieves the officials of a hotel by its ID.
-func (s *HotelService) ListOfficials(ctx context.Context, id uuid.UUID) (*models.HotelOfficials, error) {
-	hotel, err := s.repo.GetByID(ctx, id)
+// ListHotels returns a page of hotels matching the filter and the cursor for the following page.
+// The cursor is an opaque token; an empty token starts from the beginning.
+func (s *HotelService) ListHotels(ctx context.Context, filter models.HotelFilter, cursor string) (*models.HotelPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if after.Descending != filter.Descending {
+			return nil, ErrCursorSortMismatch // Its position means nothing in the other order
+		}
+		filter.After = after
+	}
+
+	// Fetch one extra row to find out whether another page exists
+	limit := filter.Limit
+	filter.Limit++
+	hotels, err := s.repo.List(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.HotelOfficials{
-		HotelID:         hotel.ID,
-		OfficialName:    hotel.OfficialName,
-		OfficialSurname: hotel.OfficialSurname,
-	}, nil
+	page := &models.HotelPage{Hotels: hotels}
+	if len(hotels) > limit {
+		page.Hotels = hotels[:limit]
+		last := page.Hotels[limit-1]
+		page.NextCursor = encodeCursor(&models.HotelCursor{CreatedAt: last.CreatedAt, ID: last.ID, Descending: filter.Descending})
+	}
+	if page.Hotels == nil {
+		page.Hotels = []*models.Hotel{} // Render an empty page as [] rather than null
+	}
+	return page, nil
+}
+
+// NearbyHotels returns up to filter.Limit hotels within filter.RadiusKm kilometers of the
+// center, closest first, each with its distance.
+func (s *HotelService) NearbyHotels(ctx context.Context, filter models.NearbyFilter) ([]*models.NearbyHotel, error) {
+	var v validator
+	v.coordinates(&filter.Latitude, &filter.Longitude)
+	if math.IsNaN(filter.RadiusKm) || filter.RadiusKm <= 0 || filter.RadiusKm > MaxNearbyRadiusKm {
+		v.add("radius_km", fmt.Sprintf("must be greater than 0 and at most %d", MaxNearbyRadiusKm))
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	return s.repo.Nearby(ctx, filter)
+}
+
+// now returns the current time truncated to the microsecond precision Postgres stores,
+// so versions handed to clients compare equal to the persisted updated_at.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// Sort keys recorded in cursors, as accepted by the sort parameter of the listing.
+const (
+	cursorSortAscending  = "created_at"
+	cursorSortDescending = "-created_at"
+)
+
+// encodeCursor serializes a keyset position and the order it belongs to into an opaque
+// URL-safe token.
+func encodeCursor(c *models.HotelCursor) string {
+	sort := cursorSortAscending
+	if c.Descending {
+		sort = cursorSortDescending
+	}
+	raw := sort + "|" + c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a token produced by encodeCursor.
+func decodeCursor(token string) (*models.HotelCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+	var descending bool
+	switch parts[0] {
+	case cursorSortAscending:
+	case cursorSortDescending:
+		descending = true
+	default:
+		return nil, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	u, err := uuid.Parse(parts[2])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &models.HotelCursor{CreatedAt: t, ID: u, Descending: descending}, nil
 }
 
 // GetHotelsByLocation fetches hotels based on the provided location argument
@@ -59,4 +259,4 @@ func (s *HotelService) GetHotelsByLocation(ctx context.Context, location string)
 // GetContactsByLocation fetches contacts based on the provided location argument
 func (s *HotelService) GetContactsByLocation(ctx context.Context, location string) ([]*models.Contact, error) {
 	return s.repo.GetContactsByLocation(ctx, location)
-}
\ No newline at end of file
+}
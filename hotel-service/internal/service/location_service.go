@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+)
+
+// LocationService provides methods to manage the locations hotels are assigned to.
+type LocationService struct {
+	repo *repository.LocationRepository // Repository for location data
+}
+
+// NewLocationService creates a new instance of LocationService.
+func NewLocationService(repo *repository.LocationRepository) *LocationService {
+	return &LocationService{repo: repo}
+}
+
+// ListLocations retrieves all locations ordered by name.
+func (s *LocationService) ListLocations(ctx context.Context) ([]*models.Location, error) {
+	return s.repo.List(ctx)
+}
+
+// GetLocation retrieves a location by its ID.
+func (s *LocationService) GetLocation(ctx context.Context, id uuid.UUID) (*models.Location, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// CreateLocation adds a new location. Names that only differ in case, accents or
+// whitespace from an existing location's name are rejected as a conflict.
+func (s *LocationService) CreateLocation(ctx context.Context, location *models.Location) error {
+	if err := validateLocation(location); err != nil {
+		return err // Reject invalid input before touching the database
+	}
+	location.ID = uuid.New()
+	location.CreatedAt = now()
+	location.UpdatedAt = location.CreatedAt
+	return s.repo.Create(ctx, location)
+}
+
+// UpdateLocation replaces a location. Renaming it renames the hotels assigned to it, and each
+// renamed hotel is announced with a HotelUpdated event.
+func (s *LocationService) UpdateLocation(ctx context.Context, location *models.Location) error {
+	if err := validateLocation(location); err != nil {
+		return err
+	}
+	location.UpdatedAt = now()
+
+	return s.repo.Update(ctx, location, func(hotel *models.Hotel) repository.Announcement {
+		return announce(events.HotelUpdated, hotel)
+	})
+}
+
+// DeleteLocation removes a location that no hotel is assigned to.
+func (s *LocationService) DeleteLocation(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Delete(ctx, id)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+)
+
+// OfficialService provides methods to manage hotel officials.
+type OfficialService struct {
+	repo *repository.OfficialRepository // Repository for official data
+}
+
+// NewOfficialService creates a new instance of OfficialService.
+func NewOfficialService(repo *repository.OfficialRepository) *OfficialService {
+	return &OfficialService{repo: repo}
+}
+
+// ListOfficials retrieves the officials of a hotel by its ID.
+func (s *OfficialService) ListOfficials(ctx context.Context, hotelID uuid.UUID) ([]*models.Official, error) {
+	return s.repo.GetByHotelID(ctx, hotelID)
+}
+
+// AddOfficial adds a new official to a hotel.
+func (s *OfficialService) AddOfficial(ctx context.Context, official *models.Official) error {
+	if err := validateOfficial(official); err != nil {
+		return err // Reject invalid input before touching the database
+	}
+	official.ID = uuid.New()
+	official.CreatedAt = now()
+	official.UpdatedAt = official.CreatedAt
+	return s.repo.Create(ctx, official, announce(events.OfficialAdded, official))
+}
+
+// RemoveOfficial removes an official from a hotel. The primary official cannot be removed
+// because it mirrors the official registered on the hotel record.
+func (s *OfficialService) RemoveOfficial(ctx context.Context, hotelID, id uuid.UUID) error {
+	official, err := s.repo.GetByID(ctx, hotelID, id)
+	if err != nil {
+		return err
+	}
+	if official.Role == models.OfficialRolePrimary {
+		return domain.Conflict("the primary official is managed through the hotel record", nil)
+	}
+	return s.repo.Delete(ctx, hotelID, id, announce(events.OfficialRemoved, events.OfficialRemovedPayload{ID: id, HotelID: hotelID}))
+}
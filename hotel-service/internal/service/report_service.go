@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/messaging"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+)
+
+// ReportService provides methods to manage report requests.
+type ReportService struct {
+	repo   *repository.ReportRepository // Repository for report request data
+	hotels *repository.HotelRepository  // Repository the synchronous statistics are computed from
+}
+
+// NewReportService creates a new instance of ReportService.
+func NewReportService(repo *repository.ReportRepository, hotels *repository.HotelRepository) *ReportService {
+	return &ReportService{repo: repo, hotels: hotels}
+}
+
+// RequestReport persists a pending report request for the location and queues it for the
+// report service through the outbox. The request is delivered at least once, even if the
+// message broker is unreachable at the time of the call.
+func (s *ReportService) RequestReport(ctx context.Context, location string) (*models.ReportRequest, error) {
+	var v validator
+	v.text("location", &location, maxLocationLength)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	request := &models.ReportRequest{
+		ID:        uuid.New(),                 // Generate a new UUID for the report
+		Status:    models.ReportStatusPending, // Set the initial status of the report
+		Location:  location,
+		CreatedAt: now(),
+	}
+	request.UpdatedAt = request.CreatedAt
+
+	// The report service receives the request wrapped in a versioned event envelope
+	event, err := events.New(ctx, events.ReportRequested, request)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	msg := &models.OutboxMessage{
+		ID:            event.ID, // Published as the message ID
+		Destination:   messaging.ReportRequestsQueue,
+		CorrelationID: request.ID.String(),
+		Payload:       payload,
+		CreatedAt:     request.CreatedAt,
+	}
+
+	if err := s.repo.Create(ctx, request, msg); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// LocationStats computes the statistics of a location report synchronously, without a round
+// trip through the report service. It is the reference for the asynchronous report's numbers.
+func (s *ReportService) LocationStats(ctx context.Context, location string) (*models.LocationStats, error) {
+	var v validator
+	v.text("location", &location, maxLocationLength)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return s.hotels.StatsForLocation(ctx, location)
+}
+
+// StatsByLocation computes the statistics of every location that has hotels.
+func (s *ReportService) StatsByLocation(ctx context.Context, sort models.LocationStatisticsSort) ([]*models.LocationStatistics, error) {
+	return s.hotels.StatsByLocation(ctx, sort)
+}
+
+// GetReport retrieves a report request by its ID.
+func (s *ReportService) GetReport(ctx context.Context, id uuid.UUID) (*models.ReportRequest, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// ListReports retrieves the most recent report requests, newest first.
+func (s *ReportService) ListReports(ctx context.Context, filter models.ReportFilter) ([]*models.ReportRequest, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+	return s.repo.List(ctx, filter)
+}
+
+// UpdateReportStatus applies a status update emitted by the report service. Updates that would
+// move a request backwards, such as a redelivered "processing" after "completed", are ignored.
+func (s *ReportService) UpdateReportStatus(ctx context.Context, update models.ReportStatusUpdate) error {
+	status, ok := models.ParseReportStatus(update.Status)
+	if !ok || status == models.ReportStatusPending {
+		return domain.Validation(fmt.Sprintf("invalid report status %q", update.Status))
+	}
+	_, err := s.repo.UpdateStatus(ctx, update.ID, status, now())
+	return err
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+// Search limits.
+const (
+	maxSearchTextLength = 200   // Longest query text accepted
+	maxSearchWindow     = 10000 // Elasticsearch's default max_result_window; offset plus limit may not exceed it
+)
+
+// ErrSearchUnavailable is returned when the service runs without a search backend.
+var ErrSearchUnavailable = domain.Unavailable("hotel search is not available", nil)
+
+// HotelSearcher is a search engine over hotels, either the Elasticsearch index
+// (search.Index) or PostgreSQL full-text search (repository.HotelRepository).
+type HotelSearcher interface {
+	Search(ctx context.Context, query models.HotelSearchQuery) (*models.HotelSearchResult, error)
+}
+
+// SearchService provides full-text search over hotels.
+type SearchService struct {
+	backend HotelSearcher // Search engine, nil if search is disabled
+}
+
+// NewSearchService creates a new instance of SearchService. A nil backend disables search.
+func NewSearchService(backend HotelSearcher) *SearchService {
+	return &SearchService{backend: backend}
+}
+
+// SearchHotels returns the hotels matching the query text, most relevant first.
+func (s *SearchService) SearchHotels(ctx context.Context, query models.HotelSearchQuery) (*models.HotelSearchResult, error) {
+	var v validator
+	v.text("q", &query.Text, maxSearchTextLength)
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageSize
+	}
+	if query.Limit > MaxPageSize {
+		query.Limit = MaxPageSize
+	}
+	if query.Offset < 0 {
+		v.add("offset", "must not be negative")
+	} else if query.Offset+query.Limit > maxSearchWindow {
+		v.add("offset", fmt.Sprintf("offset plus limit must be at most %d", maxSearchWindow))
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if s.backend == nil {
+		return nil, ErrSearchUnavailable
+	}
+	return s.backend.Search(ctx, query)
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+// Column limits mirrored from the migrations in internal/db/migrations.
+const (
+	maxOfficialNameLength    = 100
+	maxOfficialSurnameLength = 100
+	maxCompanyTitleLength    = 200
+	maxLocationLength        = 100
+	maxOfficialTitleLength   = 100
+	maxLocationPartLength    = 100 // Country, city and district of a location
+)
+
+var (
+	e164Pattern          = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)   // ITU-T E.164 international number
+	socialHandlePattern  = regexp.MustCompile(`^@[A-Za-z0-9_.]{1,64}$`) // Platform-agnostic @handle
+	phoneSeparators      = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+	contactTypeNamesText = contactTypeNames()
+)
+
+// contactValidators normalizes and validates contact content for each contact type.
+// A validator returns the normalized content or an error message.
+var contactValidators = map[models.ContactType]func(content string) (string, string){
+	models.ContactTypePhone:    validatePhone,
+	models.ContactTypeEmail:    validateEmail,
+	models.ContactTypeLocation: validateCoordinates,
+	models.ContactTypeWebsite:  validateWebsite,
+	models.ContactTypeFax:      validatePhone,
+	models.ContactTypeSocial:   validateSocial,
+}
+
+// validator accumulates field errors so every problem is reported at once.
+type validator struct {
+	errors []domain.FieldError
+}
+
+// add records a field error.
+func (v *validator) add(field, message string) {
+	v.errors = append(v.errors, domain.FieldError{Field: field, Message: message})
+}
+
+// text trims the value in place and checks it is present and at most max characters long.
+func (v *validator) text(field string, value *string, max int) {
+	*value = strings.TrimSpace(*value)
+	switch {
+	case *value == "":
+		v.add(field, "must not be empty")
+	case utf8.RuneCountInString(*value) > max:
+		v.add(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// optionalText trims the value in place and checks it is at most max characters long.
+func (v *validator) optionalText(field string, value *string, max int) {
+	*value = strings.TrimSpace(*value)
+	if utf8.RuneCountInString(*value) > max {
+		v.add(field, fmt.Sprintf("must be at most %d characters", max))
+	}
+}
+
+// coordinates checks that optional coordinates are given together and within range.
+func (v *validator) coordinates(latitude, longitude *float64) {
+	switch {
+	case (latitude == nil) != (longitude == nil):
+		v.add("latitude", "latitude and longitude must be given together")
+	case latitude != nil:
+		if lat := *latitude; math.IsNaN(lat) || lat < -90 || lat > 90 {
+			v.add("latitude", "must be in [-90, 90]")
+		}
+		if lon := *longitude; math.IsNaN(lon) || lon < -180 || lon > 180 {
+			v.add("longitude", "must be in [-180, 180]")
+		}
+	}
+}
+
+// err returns the accumulated errors as a validation domain error, or nil if there are none.
+func (v *validator) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return domain.InvalidFields(v.errors)
+}
+
+// validateHotel normalizes the hotel's text fields and checks them against the column limits.
+func validateHotel(hotel *models.Hotel) error {
+	var v validator
+	v.text("official_name", &hotel.OfficialName, maxOfficialNameLength)
+	v.text("official_surname", &hotel.OfficialSurname, maxOfficialSurnameLength)
+	v.text("company_title", &hotel.CompanyTitle, maxCompanyTitleLength)
+	v.text("location", &hotel.Location, maxLocationLength)
+	v.coordinates(hotel.Latitude, hotel.Longitude)
+	return v.err()
+}
+
+// validateLocation normalizes the location's text fields and checks its coordinates.
+func validateLocation(location *models.Location) error {
+	var v validator
+	v.text("name", &location.Name, maxLocationLength)
+	v.optionalText("country", &location.Country, maxLocationPartLength)
+	v.optionalText("city", &location.City, maxLocationPartLength)
+	v.optionalText("district", &location.District, maxLocationPartLength)
+
+	v.coordinates(location.Latitude, location.Longitude)
+	return v.err()
+}
+
+// validateOfficial normalizes an official added through the API and checks its fields.
+func validateOfficial(official *models.Official) error {
+	var v validator
+	v.text("name", &official.Name, maxOfficialNameLength)
+	v.text("surname", &official.Surname, maxOfficialSurnameLength)
+
+	official.Title = strings.TrimSpace(official.Title)
+	if utf8.RuneCountInString(official.Title) > maxOfficialTitleLength {
+		v.add("title", fmt.Sprintf("must be at most %d characters", maxOfficialTitleLength))
+	}
+
+	official.Role = models.OfficialRole(strings.ToUpper(strings.TrimSpace(string(official.Role))))
+	switch {
+	case official.Role == models.OfficialRolePrimary:
+		v.add("role", "the primary official is managed through the hotel record")
+	case !knownOfficialRole(official.Role):
+		v.add("role", "must be one of OWNER, GENERAL_MANAGER, SALES_DIRECTOR, OTHER")
+	}
+
+	if official.ActiveFrom != nil && official.ActiveTo != nil && official.ActiveTo.Before(official.ActiveFrom.Time) {
+		v.add("active_to", "must not be before active_from")
+	}
+	return v.err()
+}
+
+// knownOfficialRole reports whether role is one of models.OfficialRoles.
+func knownOfficialRole(role models.OfficialRole) bool {
+	for _, r := range models.OfficialRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// validateContact normalizes the contact's type and content and validates the content for its type.
+func validateContact(contact *models.Contact) error {
+	var v validator
+	contact.Content = strings.TrimSpace(contact.Content)
+
+	contactType, known := models.ParseContactType(string(contact.Type))
+	switch {
+	case strings.TrimSpace(string(contact.Type)) == "":
+		v.add("type", "must not be empty")
+	case !known:
+		v.add("type", "must be one of "+contactTypeNamesText)
+	default:
+		contact.Type = contactType
+	}
+
+	switch {
+	case contact.Content == "":
+		v.add("content", "must not be empty")
+	case known:
+		normalized, message := contactValidators[contactType](contact.Content)
+		if message != "" {
+			v.add("content", message)
+		} else {
+			contact.Content = normalized
+		}
+	}
+	return v.err()
+}
+
+// contactTypeNames lists the valid contact types for error messages.
+func contactTypeNames() string {
+	names := make([]string, len(models.ContactTypes))
+	for i, t := range models.ContactTypes {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+// validatePhone accepts international numbers with common separators and normalizes them to E.164.
+func validatePhone(content string) (string, string) {
+	number := phoneSeparators.Replace(content)
+	if strings.HasPrefix(number, "00") {
+		number = "+" + number[2:] // International call prefix
+	}
+	if !e164Pattern.MatchString(number) {
+		return "", "must be an international phone number in E.164 format, e.g. +902121234567"
+	}
+	return number, ""
+}
+
+// validateEmail accepts a bare RFC 5322 address without a display name.
+func validateEmail(content string) (string, string) {
+	address, err := mail.ParseAddress(content)
+	if err != nil || address.Address != content || address.Name != "" {
+		return "", "must be a valid email address"
+	}
+	return address.Address, ""
+}
+
+// validateWebsite accepts absolute http and https URLs.
+func validateWebsite(content string) (string, string) {
+	u, err := url.Parse(content)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "must be an absolute http or https URL"
+	}
+	return u.String(), ""
+}
+
+// validateSocial accepts a profile URL or an @handle.
+func validateSocial(content string) (string, string) {
+	if socialHandlePattern.MatchString(content) {
+		return content, ""
+	}
+	if normalized, message := validateWebsite(content); message == "" {
+		return normalized, ""
+	}
+	return "", "must be a profile URL or an @handle"
+}
+
+// validateCoordinates accepts "latitude,longitude" pairs and normalizes the spacing.
+func validateCoordinates(content string) (string, string) {
+	const message = `must be "latitude,longitude" with latitude in [-90, 90] and longitude in [-180, 180]`
+	latText, lonText, found := strings.Cut(content, ",")
+	if !found {
+		return "", message
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	if err != nil || math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return "", message
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lonText), 64)
+	if err != nil || math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return "", message
+	}
+	return strconv.FormatFloat(lat, 'f', -1, 64) + "," + strconv.FormatFloat(lon, 'f', -1, 64), ""
+}
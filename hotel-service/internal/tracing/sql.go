@@ -0,0 +1,168 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SQLConnector returns a connector opening connections with drv and dsn that record a client
+// span for every query and statement executed within a recording span, e.g. that of an HTTP
+// request. The span is named after the SQL operation and carries the statement text, never its
+// arguments. Work outside of a trace, such as health checks and outbox polling, is not traced.
+func SQLConnector(drv driver.Driver, dsn string) driver.Connector {
+	return &sqlConnector{driver: drv, dsn: dsn}
+}
+
+// sqlConnector implements driver.Connector for SQLConnector.
+type sqlConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+// Connect implements driver.Connector.
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: conn}, nil
+}
+
+// Driver implements driver.Connector.
+func (c *sqlConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sqlConn traces the queries run on a driver connection. It forwards the optional driver
+// interfaces, and returns driver.ErrSkip where the wrapped connection lacks one so database/sql
+// falls back as it would without the wrapper.
+type sqlConn struct {
+	driver.Conn
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSQLSpan(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	endSQLSpan(span, err)
+	return rows, err
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := startSQLSpan(ctx, query)
+	result, err := execer.ExecContext(ctx, query, args)
+	endSQLSpan(span, err)
+	return result, err
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+// BeginTx implements driver.ConnBeginTx.
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin() // Drivers without BeginTx ignore opts
+}
+
+// Ping implements driver.Pinger.
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator.
+func (c *sqlConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// startSQLSpan starts the span of a statement if ctx belongs to a recording span.
+func startSQLSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx, nil
+	}
+	operation := sqlOperation(query)
+	return Tracer().Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationKey.String(operation),
+			semconv.DBStatementKey.String(strings.TrimSpace(query)),
+		),
+	)
+}
+
+// endSQLSpan ends a span started by startSQLSpan, recording err. A nil span is ignored.
+func endSQLSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil && err != driver.ErrSkip {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// sqlOperation returns the leading keyword of a statement, e.g. SELECT, or WITH for a statement
+// starting with a common table expression.
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+	return strings.ToUpper(strings.TrimLeft(fields[0], "("))
+}
+
+// Ensure the wrapper keeps the interfaces database/sql looks for.
+var (
+	_ driver.QueryerContext     = (*sqlConn)(nil)
+	_ driver.ExecerContext      = (*sqlConn)(nil)
+	_ driver.ConnPrepareContext = (*sqlConn)(nil)
+	_ driver.ConnBeginTx        = (*sqlConn)(nil)
+	_ driver.Pinger             = (*sqlConn)(nil)
+	_ driver.SessionResetter    = (*sqlConn)(nil)
+	_ driver.Validator          = (*sqlConn)(nil)
+	_ driver.NamedValueChecker  = (*sqlConn)(nil)
+)
@@ -0,0 +1,153 @@
+// File: tests/contract/events_test.go
+
+// Package contract pins the wire format of the events hotel-service publishes to other services.
+// A failing golden test means consumers would see a different message: either restore the old
+// format or publish the change under a new event version. Run with -update to accept a change.
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// fixedTime is the timestamp used in every golden event.
+var fixedTime = time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+// goldenEvents lists the events published by the service, with fixed payloads.
+var goldenEvents = []struct {
+	eventType events.Type
+	payload   interface{}
+}{
+	{events.ReportRequested, models.ReportRequest{
+		ID:        uuid.MustParse("7d4f3b1c-5a2e-4c8f-9b6d-1e2f3a4b5c6d"),
+		Status:    models.ReportStatusPending,
+		Location:  "Istanbul",
+		CreatedAt: fixedTime,
+		UpdatedAt: fixedTime,
+	}},
+	{events.HotelCreated, goldenHotel},
+	{events.HotelUpdated, goldenHotel},
+	{events.HotelDeleted, events.HotelDeletedPayload{ID: goldenHotel.ID}},
+	{events.ContactAdded, models.Contact{
+		ID:        uuid.MustParse("3c2b1a09-8f7e-4d6c-b5a4-938271605f4e"),
+		HotelID:   goldenHotel.ID,
+		Type:      models.ContactTypePhone,
+		Content:   "+902121234567",
+		CreatedAt: fixedTime,
+		UpdatedAt: fixedTime,
+	}},
+	{events.ContactUpdated, models.Contact{
+		ID:        uuid.MustParse("3c2b1a09-8f7e-4d6c-b5a4-938271605f4e"),
+		HotelID:   goldenHotel.ID,
+		Type:      models.ContactTypeEmail,
+		Content:   "info@bogazotel.example",
+		CreatedAt: fixedTime,
+		UpdatedAt: fixedTime.Add(time.Hour),
+	}},
+	{events.ContactRemoved, events.ContactRemovedPayload{
+		ID:      uuid.MustParse("3c2b1a09-8f7e-4d6c-b5a4-938271605f4e"),
+		HotelID: goldenHotel.ID,
+	}},
+	{events.OfficialAdded, models.Official{
+		ID:         uuid.MustParse("5e4d3c2b-1a09-4f8e-8d7c-6b5a49382716"),
+		HotelID:    goldenHotel.ID,
+		Name:       "Mehmet",
+		Surname:    "Öztürk",
+		Role:       models.OfficialRoleGeneralManager,
+		Title:      "Genel Müdür",
+		ActiveFrom: &models.Date{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		CreatedAt:  fixedTime,
+		UpdatedAt:  fixedTime,
+	}},
+	{events.OfficialRemoved, events.OfficialRemovedPayload{
+		ID:      uuid.MustParse("5e4d3c2b-1a09-4f8e-8d7c-6b5a49382716"),
+		HotelID: goldenHotel.ID,
+	}},
+}
+
+// goldenHotel is the hotel used in the hotel and contact events.
+var goldenHotel = models.Hotel{
+	ID:              uuid.MustParse("a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d"),
+	OfficialName:    "Ayşe",
+	OfficialSurname: "Yılmaz",
+	CompanyTitle:    "Boğaz Otelcilik A.Ş.",
+	Location:        "Istanbul",
+	CreatedAt:       fixedTime,
+	UpdatedAt:       fixedTime,
+}
+
+// goldenEnvelope builds an event with a fixed ID, time and trace ID.
+func goldenEnvelope(t *testing.T, eventType events.Type, payload interface{}) []byte {
+	ctx := events.ContextWithTraceID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	envelope, err := events.New(ctx, eventType, payload)
+	require.NoError(t, err)
+	envelope.ID = uuid.MustParse("0b8e4f6a-2c1d-4e3f-8a9b-7c6d5e4f3a2b")
+	envelope.OccurredAt = fixedTime
+
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	require.NoError(t, err)
+	return append(data, '\n')
+}
+
+func TestEventsMatchGoldenFiles(t *testing.T) {
+	for _, tc := range goldenEvents {
+		t.Run(tc.eventType.String(), func(t *testing.T) {
+			got := goldenEnvelope(t, tc.eventType, tc.payload)
+			path := filepath.Join("testdata", tc.eventType.String()+".golden.json")
+
+			if *update {
+				require.NoError(t, os.WriteFile(path, got, 0o644))
+			}
+			want, err := os.ReadFile(path)
+			require.NoError(t, err, "missing golden file, run with -update to create it")
+			assert.JSONEq(t, string(want), string(got))
+		})
+	}
+}
+
+func TestGoldenFilesMatchSchemas(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.golden.json"))
+	require.NoError(t, err)
+	require.Len(t, files, len(goldenEvents), "every golden file must belong to a published event")
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.NoError(t, events.Validate(data), file)
+	}
+}
+
+func TestSchemaRejectsBreakingPayload(t *testing.T) {
+	// A report request without a location must never reach the report service
+	_, err := events.New(context.Background(), events.ReportRequested, map[string]interface{}{
+		"id":         uuid.NewString(),
+		"status":     "pending",
+		"created_at": fixedTime,
+		"updated_at": fixedTime,
+	})
+	assert.Error(t, err)
+
+	_, err = events.New(context.Background(), events.Type{Name: "report.requested", Version: 99}, struct{}{})
+	assert.Error(t, err, "unregistered versions are rejected")
+}
+
+func TestSchemasArePublished(t *testing.T) {
+	for _, tc := range goldenEvents {
+		schema, err := events.Schema(tc.eventType)
+		require.NoError(t, err)
+		assert.True(t, json.Valid(schema))
+	}
+}
@@ -1,8 +1,453 @@
 package unit
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tfgoztok/hotel-service/internal/api"
+	"github.com/tfgoztok/hotel-service/internal/health"
+	"github.com/tfgoztok/hotel-service/internal/metrics"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
 )
 
-func TestHandlers(t *testing.T) {
+// problemBody mirrors the RFC 7807 body written by the handlers.
+type problemBody struct {
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Errors []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// newTestRouter builds the API router on top of a sqlmock database.
+func newTestRouter(t *testing.T) (http.Handler, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return api.NewRouter(db, logger.New(), nil, nil, health.NewRegistry(logger.New()), metrics.New()), mock
+}
+
+func TestGetHotelDetailsNotFound(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	hotelID := uuid.New()
+	mock.ExpectQuery("SELECT (.+) FROM hotels").
+		WithArgs(hotelID).
+		WillReturnError(sql.ErrNoRows)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hotels/"+hotelID.String(), nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	var body problemBody
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, http.StatusNotFound, body.Status)
+	assert.Equal(t, "hotel not found", body.Detail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteHotelNotFound(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	hotelID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM hotels").
+		WithArgs(hotelID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/hotels/"+hotelID.String(), nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInternalErrorsAreNotLeaked(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	hotelID := uuid.New()
+	mock.ExpectQuery("SELECT (.+) FROM hotels").
+		WithArgs(hotelID).
+		WillReturnError(assert.AnError)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hotels/"+hotelID.String(), nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), assert.AnError.Error())
+}
+
+func TestCreateHotelValidationFailure(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	body := strings.NewReader(`{"official_name":"John","official_surname":"Doe","company_title":"","location":"Istanbul"}`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/hotels", body))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var problem problemBody
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+	require.Len(t, problem.Errors, 1)
+	assert.Equal(t, "company_title", problem.Errors[0].Field)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateHotelMatchesAnyStrongETagInTheList(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	hotelID := uuid.New()
+	version := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	created := version.Add(-time.Hour)
+	// The weak tag is skipped, since If-Match compares entity tags strongly
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE hotels").
+		WithArgs(hotelID, "John", "Doe", "Hotel", "Istanbul", sqlmock.AnyArg(), pq.Array([]string{"2023-01-01T00:00:00Z", version.Format(time.RFC3339Nano)}), nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"location", "created_at", "updated_at"}).AddRow("Istanbul", created, version.Add(time.Minute)))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	body := strings.NewReader(`{"official_name":"John","official_surname":"Doe","company_title":"Hotel","location":"Istanbul"}`)
+	req := httptest.NewRequest(http.MethodPut, "/hotels/"+hotelID.String(), body)
+	req.Header.Set("If-Match", `"1672531200000000", W/"1", "`+strconv.FormatInt(version.UnixMicro(), 10)+`"`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateHotelWithWildcardIfMatchRequiresAnExistingHotel(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	hotelID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE hotels").WillReturnRows(sqlmock.NewRows([]string{"location", "created_at", "updated_at"}))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(hotelID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	body := strings.NewReader(`{"official_name":"John","official_surname":"Doe","company_title":"Hotel","location":"Istanbul"}`)
+	req := httptest.NewRequest(http.MethodPut, "/hotels/"+hotelID.String(), body)
+	req.Header.Set("If-Match", "*")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateHotelWithOnlyWeakETagsFailsThePrecondition(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	body := strings.NewReader(`{"official_name":"John","official_surname":"Doe","company_title":"Hotel","location":"Istanbul"}`)
+	req := httptest.NewRequest(http.MethodPut, "/hotels/"+uuid.New().String(), body)
+	req.Header.Set("If-Match", `W/"1672531200000000"`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
+
+func TestGraphQLContactTypeEnum(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM contacts c").
+		WithArgs("Istanbul").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hotel_id", "type", "content"}).
+			AddRow(uuid.New(), uuid.New(), "PHONE", "+902121234567"))
+
+	body := strings.NewReader(`{"query":"{ contactsByLocation(location: \"Istanbul\") { Type } }"}`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", body))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"contactsByLocation":[{"Type":"PHONE"}]}}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListContactsOfMissingHotelNotFound(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	hotelID := uuid.New()
+	mock.ExpectQuery("SELECT (.+) FROM contacts").
+		WithArgs(hotelID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hotel_id", "type", "content", "created_at", "updated_at"}))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(hotelID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hotels/"+hotelID.String()+"/contacts", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteContactOfAnotherHotel(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	hotelID, contactID := uuid.New(), uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM contacts").
+		WithArgs(contactID, hotelID).
+		WillReturnResult(sqlmock.NewResult(0, 0)) // The contact exists, but not under this hotel
+	mock.ExpectRollback()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/hotels/"+hotelID.String()+"/contacts/"+contactID.String(), nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReportReturnsStatus(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	reportID := uuid.New()
+	mock.ExpectQuery("SELECT (.+) FROM report_requests").
+		WithArgs(reportID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "location", "status", "created_at", "updated_at"}).
+			AddRow(reportID, "Istanbul", "processing", time.Now(), time.Now()))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports/"+reportID.String(), nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var body models.ReportRequest
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, reportID, body.ID)
+	assert.Equal(t, models.ReportStatusProcessing, body.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateLocationWithFoldedDuplicateNameConflicts(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	mock.ExpectExec("INSERT INTO locations").
+		WithArgs(sqlmock.AnyArg(), "istanbul", "", "", "", nil, nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/locations", strings.NewReader(`{"name":" istanbul "}`)))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// approx is a sqlmock argument matcher for floats computed by the repository.
+type approx float64
+
+func (a approx) Match(v driver.Value) bool {
+	f, ok := v.(float64)
+	return ok && math.Abs(f-float64(a)) < 1e-6
+}
+
+// nearbyColumns are the columns selected by the proximity search.
+var nearbyColumns = []string{"id", "official_name", "official_surname", "company_title", "location", "latitude", "longitude", "created_at", "updated_at", "distance_km"}
+
+func TestNearbyHotelsIsNotShadowedByHotelID(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	id := uuid.New()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 111.19508 km is one degree of arc, so the bounding box spans one degree around the equator
+	mock.ExpectQuery(`WHERE latitude BETWEEN \$3 AND \$4 AND longitude BETWEEN \$5 AND \$6(.+)ORDER BY distance_km, id`).
+		WithArgs(approx(0), approx(10), approx(-1), approx(1), approx(9), approx(11), sqlmock.AnyArg(), approx(111.19508), 5).
+		WillReturnRows(sqlmock.NewRows(nearbyColumns).AddRow(id, "John", "Doe", "Hotel", "Istanbul", 0.5, 10.0, created, created, 55.6))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hotels/nearby?lat=0&lon=10&radius_km=111.19508&limit=5", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":"`+id.String()+`","official_name":"John","official_surname":"Doe","company_title":"Hotel","location":"Istanbul",
+		"latitude":0.5,"longitude":10,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z","distance_km":55.6}]`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNearbyHotelsAcrossTheAntimeridianSearchesAllLongitudes(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	mock.ExpectQuery("ORDER BY distance_km").
+		WithArgs(approx(-17), approx(179.9), sqlmock.AnyArg(), sqlmock.AnyArg(), approx(-180), approx(180), sqlmock.AnyArg(), approx(50), 20).
+		WillReturnRows(sqlmock.NewRows(nearbyColumns))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hotels/nearby?lat=-17&lon=179.9&radius_km=50", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNearbyHotelsRejectsInvalidParameters(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	for query, status := range map[string]int{
+		"lon=10&radius_km=5":               http.StatusBadRequest,
+		"lat=abc&lon=10&radius_km=5":       http.StatusBadRequest,
+		"lat=91&lon=10&radius_km=5":        http.StatusUnprocessableEntity,
+		"lat=0&lon=10&radius_km=0":         http.StatusUnprocessableEntity,
+		"lat=0&lon=10&radius_km=5000":      http.StatusUnprocessableEntity,
+		"lat=0&lon=10&radius_km=5&limit=0": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hotels/nearby?"+query, nil))
+
+		assert.Equal(t, status, rec.Code, query)
+	}
+}
+
+func TestGraphQLHotelsNear(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("ORDER BY distance_km").
+		WillReturnRows(sqlmock.NewRows(nearbyColumns).AddRow(uuid.New(), "John", "Doe", "Hotel", "Istanbul", 41.0, 29.0, created, created, 1.25))
+
+	body := strings.NewReader(`{"query":"{ hotelsNear(lat: 41, lon: 29, radiusKm: 10) { companyTitle latitude distanceKm } }"}`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", body))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"hotelsNear":[{"companyTitle":"Hotel","latitude":41,"distanceKm":1.25}]}}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListReportsRejectsUnknownStatus(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports?status=archived", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestLocationStatsIsNotShadowedByReportID(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT h.id\\)").
+		WithArgs("Istanbul").
+		WillReturnRows(sqlmock.NewRows([]string{"hotels", "phones"}).AddRow(3, 5))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports/location-stats?location=Istanbul", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"location":"Istanbul","hotel_count":3,"phone_count":5}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLocationStatsRequiresLocation(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reports/location-stats", nil))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+// locationStatisticsRows returns the rows of the grouped location statistics query.
+func locationStatisticsRows() *sqlmock.Rows {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return sqlmock.NewRows([]string{"location", "hotel_count", "phone_count", "email_count", "last_updated_at"}).
+		AddRow("Istanbul", 3, 5, 2, updated).
+		AddRow("=Ankara", 1, 0, 0, nil)
+}
+
+func TestLocationStatisticsSortsAndReturnsJSON(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	mock.ExpectQuery("GROUP BY h.location\\s+ORDER BY hotel_count DESC NULLS LAST, location ASC").
+		WillReturnRows(locationStatisticsRows())
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/locations?sort=-hotel_count", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[
+		{"location":"Istanbul","hotel_count":3,"phone_count":5,"email_count":2,"last_updated_at":"2024-05-01T12:00:00Z"},
+		{"location":"=Ankara","hotel_count":1,"phone_count":0,"email_count":0,"last_updated_at":"0001-01-01T00:00:00Z"}
+	]`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLocationStatisticsAsCSV(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	mock.ExpectQuery("ORDER BY location ASC").WillReturnRows(locationStatisticsRows())
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/locations", nil)
+	req.Header.Set("Accept", "text/csv")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "location,hotel_count,phone_count,email_count,last_updated_at\n"+
+		"Istanbul,3,5,2,2024-05-01T12:00:00Z\n"+
+		"'=Ankara,1,0,0,\n", rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLocationStatisticsRejectsInvalidParameters(t *testing.T) {
+	router, _ := newTestRouter(t)
+
+	for _, query := range []string{"sort=updated_at", "sort=-", "format=xml"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats/locations?"+query, nil))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestGraphQLLocationStatistics(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	mock.ExpectQuery("ORDER BY email_count ASC").WillReturnRows(locationStatisticsRows())
+
+	body := strings.NewReader(`{"query":"{ locationStatistics(sort: \"email_count\") { location emailCount lastUpdatedAt } }"}`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", body))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"locationStatistics":[
+		{"location":"Istanbul","emailCount":2,"lastUpdatedAt":"2024-05-01T12:00:00Z"},
+		{"location":"=Ankara","emailCount":0,"lastUpdatedAt":"0001-01-01T00:00:00Z"}
+	]}}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraphQLLocationStats(t *testing.T) {
+	router, mock := newTestRouter(t)
+
+	mock.ExpectQuery("SELECT COUNT\\(DISTINCT h.id\\)").
+		WithArgs("Istanbul").
+		WillReturnRows(sqlmock.NewRows([]string{"hotels", "phones"}).AddRow(2, 1))
+
+	body := strings.NewReader(`{"query":"{ locationStats(location: \"Istanbul\") { location hotelCount phoneCount } }"}`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/graphql", body))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"locationStats":{"location":"Istanbul","hotelCount":2,"phoneCount":1}}}`, rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -0,0 +1,154 @@
+// File: tests/unit/outbox_test.go
+
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/tfgoztok/hotel-service/internal/messaging"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
+)
+
+// fakeOutboxStore is an in-memory OutboxStore recording the dispatcher's calls
+type fakeOutboxStore struct {
+	pending []*models.OutboxMessage
+	sent    []uuid.UUID
+	failed  map[uuid.UUID]time.Time
+}
+
+func (s *fakeOutboxStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxMessage, error) {
+	if limit > len(s.pending) {
+		limit = len(s.pending)
+	}
+	claimed := s.pending[:limit]
+	s.pending = s.pending[limit:]
+	return claimed, nil
+}
+
+func (s *fakeOutboxStore) MarkSent(ctx context.Context, id uuid.UUID) error {
+	s.sent = append(s.sent, id)
+	return nil
+}
+
+func (s *fakeOutboxStore) MarkFailed(ctx context.Context, id uuid.UUID, cause string, nextAttempt time.Time) error {
+	if s.failed == nil {
+		s.failed = make(map[uuid.UUID]time.Time)
+	}
+	s.failed[id] = nextAttempt
+	return nil
+}
+
+// failingRabbitMQ is a broker that rejects every publish
+type failingRabbitMQ struct{}
+
+func (failingRabbitMQ) PublishReportRequest(ctx context.Context, queueName string, reportRequest interface{}) error {
+	return errors.New("connection closed")
+}
+
+func (failingRabbitMQ) Publish(ctx context.Context, msg messaging.Message) error {
+	return errors.New("connection closed")
+}
+
+func (failingRabbitMQ) Consume(queueName string, handler messaging.DeliveryHandler) error {
+	return errors.New("connection closed")
+}
+
+func (failingRabbitMQ) Close() {}
+
+func TestDispatcherPublishesAndMarksSent(t *testing.T) {
+	msg := &models.OutboxMessage{ID: uuid.New(), Destination: "report_requests", CorrelationID: "test-id", Payload: []byte(`{"id":"test-id"}`)}
+	store := &fakeOutboxStore{pending: []*models.OutboxMessage{msg}}
+	broker := &MockRabbitMQ{}
+
+	n, err := messaging.NewDispatcher(store, broker, logger.New()).DispatchPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []uuid.UUID{msg.ID}, store.sent)
+	assert.Len(t, broker.publishedMessages, 1)
+	assert.JSONEq(t, `{"id":"test-id"}`, string(broker.publishedMessages[0]))
+	// The outbox ID is the message ID, so redeliveries can be recognized
+	assert.Equal(t, msg.ID.String(), broker.published[0].ID)
+	assert.Equal(t, "test-id", broker.published[0].CorrelationID)
+}
+
+func TestDispatcherBacksOffFailedMessages(t *testing.T) {
+	msg := &models.OutboxMessage{ID: uuid.New(), Destination: "report_requests", Payload: []byte(`{}`), Attempts: 3}
+	store := &fakeOutboxStore{pending: []*models.OutboxMessage{msg}}
+
+	before := time.Now()
+	_, err := messaging.NewDispatcher(store, failingRabbitMQ{}, logger.New()).DispatchPending(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, store.sent)
+	assert.Contains(t, store.failed, msg.ID)
+	// Three previous failures double the base delay three times
+	assert.WithinDuration(t, before.Add(8*messaging.DefaultRetryBaseDelay), store.failed[msg.ID], time.Second)
+}
+
+func TestDispatcherFlushPublishesEveryDueMessage(t *testing.T) {
+	store := &fakeOutboxStore{}
+	for i := 0; i < 2*messaging.DefaultDispatchBatch+1; i++ {
+		store.pending = append(store.pending, &models.OutboxMessage{ID: uuid.New(), Destination: "report_requests", Payload: []byte(`{}`)})
+	}
+	broker := &MockRabbitMQ{}
+
+	err := messaging.NewDispatcher(store, broker, logger.New()).Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, store.pending)
+	assert.Len(t, store.sent, 2*messaging.DefaultDispatchBatch+1)
+}
+
+func TestDispatcherFlushReturnsWhileBrokerIsDown(t *testing.T) {
+	msg := &models.OutboxMessage{ID: uuid.New(), Destination: "report_requests", Payload: []byte(`{}`)}
+	store := &fakeOutboxStore{pending: []*models.OutboxMessage{msg}}
+
+	err := messaging.NewDispatcher(store, failingRabbitMQ{}, logger.New()).Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, store.failed, msg.ID) // Rescheduled for after the restart
+}
+
+func TestReportRepositoryCreateWritesOutboxInTransaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	request := &models.ReportRequest{ID: uuid.New(), Status: models.ReportStatusPending, Location: "Istanbul"}
+	msg := &models.OutboxMessage{ID: uuid.New(), Destination: "report_requests", Payload: []byte(`{}`)}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO report_requests").
+		WithArgs(request.ID, "Istanbul", models.ReportStatusPending, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(msg.ID, "", "report_requests", "", sqlmock.AnyArg(), []byte(nil), sqlmock.AnyArg()). // No trace context outside a span
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = repository.NewReportRepository(db).Create(context.Background(), request, msg)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReportRepositoryCreateRollsBackOnOutboxFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	request := &models.ReportRequest{ID: uuid.New(), Status: models.ReportStatusPending, Location: "Istanbul"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO report_requests").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	err = repository.NewReportRepository(db).Create(context.Background(), request, &models.OutboxMessage{ID: uuid.New()})
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,23 +3,54 @@
 package unit
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/messaging"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+	"github.com/tfgoztok/hotel-service/internal/service"
+	"github.com/tfgoztok/hotel-service/internal/tracing"
+	"github.com/tfgoztok/hotel-service/pkg/logger"
 )
 
 // Mock RabbitMQ for unit tests
 type MockRabbitMQ struct {
 	publishedMessages [][]byte
+	published         []messaging.Message
+	traceIDs          []string
+	consumers         map[string]messaging.DeliveryHandler
 }
 
-func (m *MockRabbitMQ) PublishReportRequest(queueName string, reportRequest interface{}) error {
+func (m *MockRabbitMQ) PublishReportRequest(ctx context.Context, queueName string, reportRequest interface{}) error {
 	body, _ := json.Marshal(reportRequest)
 	m.publishedMessages = append(m.publishedMessages, body)
 	return nil
 }
 
+func (m *MockRabbitMQ) Publish(ctx context.Context, msg messaging.Message) error {
+	m.published = append(m.published, msg)
+	m.traceIDs = append(m.traceIDs, tracing.TraceID(ctx))
+	return m.PublishReportRequest(ctx, msg.RoutingKey, msg.Body)
+}
+
+func (m *MockRabbitMQ) Consume(queueName string, handler messaging.DeliveryHandler) error {
+	if m.consumers == nil {
+		m.consumers = make(map[string]messaging.DeliveryHandler)
+	}
+	m.consumers[queueName] = handler
+	return nil
+}
+
 func (m *MockRabbitMQ) Close() {}
 
 // Unit test for PublishReportRequest
@@ -32,7 +63,7 @@ func TestPublishReportRequest(t *testing.T) {
 		ID: "test-id",
 	}
 
-	err := mock.PublishReportRequest("test-queue", reportRequest)
+	err := mock.PublishReportRequest(context.Background(), "test-queue", reportRequest)
 	assert.NoError(t, err)
 	assert.Len(t, mock.publishedMessages, 1)
 
@@ -43,3 +74,143 @@ func TestPublishReportRequest(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "test-id", receivedRequest.ID)
 }
+
+func TestReportStatusHandlerAppliesForwardTransitionsOnly(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	broker := &MockRabbitMQ{}
+	reportService := service.NewReportService(repository.NewReportRepository(db), repository.NewHotelRepository(db))
+	assert.NoError(t, broker.Consume(messaging.ReportStatusQueue, messaging.NewReportStatusHandler(reportService, logger.New())))
+	handle := broker.consumers[messaging.ReportStatusQueue]
+
+	reportID := uuid.New()
+	// "completed" may follow "pending" or "processing"
+	mock.ExpectExec("UPDATE report_requests").
+		WithArgs(reportID, models.ReportStatusCompleted, sqlmock.AnyArg(), pq.StringArray{"pending", "processing"}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, handle([]byte(`{"id":"`+reportID.String()+`","status":"completed"}`)))
+
+	// A late "processing" no longer matches and is ignored
+	mock.ExpectExec("UPDATE report_requests").
+		WithArgs(reportID, models.ReportStatusProcessing, sqlmock.AnyArg(), pq.StringArray{"pending"}).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS").WithArgs(reportID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	assert.NoError(t, handle([]byte(`{"id":"`+reportID.String()+`","status":"Processing"}`)))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReportStatusHandlerRejectsInvalidMessages(t *testing.T) {
+	db, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	handle := messaging.NewReportStatusHandler(service.NewReportService(repository.NewReportRepository(db), repository.NewHotelRepository(db)), logger.New())
+
+	err = handle([]byte(`not json`))
+	assert.Equal(t, domain.KindBadRequest, domain.KindOf(err))
+
+	err = handle([]byte(`{"id":"` + uuid.NewString() + `","status":"archived"}`))
+	assert.Equal(t, domain.KindValidation, domain.KindOf(err))
+}
+
+// recordingPublisher is an events.Publisher that keeps the events it is given.
+type recordingPublisher struct {
+	events []*events.Envelope
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event *events.Envelope) error {
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestEventHandlerPassesEventsToThePublisher(t *testing.T) {
+	recorder := &recordingPublisher{}
+	handle := messaging.NewEventHandler(recorder, logger.New())
+
+	event, err := events.New(context.Background(), events.HotelDeleted, events.HotelDeletedPayload{ID: uuid.New()})
+	assert.NoError(t, err)
+	msg, err := messaging.EventMessage(event)
+	assert.NoError(t, err)
+	assert.NoError(t, handle(msg.Payload))
+	if assert.Len(t, recorder.events, 1) {
+		assert.Equal(t, event.ID, recorder.events[0].ID)
+		assert.Equal(t, events.HotelDeleted.Name, recorder.events[0].Type)
+	}
+
+	// A malformed event is dropped rather than redelivered
+	err = handle([]byte(`not json`))
+	assert.Equal(t, domain.KindBadRequest, domain.KindOf(err))
+}
+
+func TestSearchIndexTopologyBindsHotelContactAndOfficialEvents(t *testing.T) {
+	topology := messaging.DefaultTopology().WithSearchIndex()
+
+	queues := map[string]amqp.Table{}
+	for _, q := range topology.Queues {
+		queues[q.Name] = q.Arguments
+	}
+	require.Contains(t, queues, messaging.SearchIndexQueue)
+	// Events the indexer fails to apply wait in a retry queue rather than being requeued at once
+	assert.Equal(t, messaging.ConsumerRetryExchange, queues[messaging.SearchIndexQueue]["x-dead-letter-exchange"])
+	assert.Equal(t, messaging.SearchIndexQueue, queues[messaging.SearchIndexQueue+".retry"]["x-dead-letter-routing-key"])
+	assert.Contains(t, queues, messaging.SearchIndexQueue+".dead")
+	assert.Contains(t, topology.Bindings, messaging.Binding{Queue: messaging.SearchIndexQueue, Exchange: messaging.HotelEventsExchange, RoutingKey: "hotel.*"})
+	assert.Contains(t, topology.Bindings, messaging.Binding{Queue: messaging.SearchIndexQueue, Exchange: messaging.HotelEventsExchange, RoutingKey: "contact.*"})
+	assert.Contains(t, topology.Bindings, messaging.Binding{Queue: messaging.SearchIndexQueue, Exchange: messaging.HotelEventsExchange, RoutingKey: "official.*"})
+	for _, q := range messaging.DefaultTopology().Queues {
+		assert.NotEqual(t, messaging.SearchIndexQueue, q.Name)
+	}
+}
+
+func TestDefaultTopologyRoutesRetriesBackToReportRequests(t *testing.T) {
+	topology := messaging.DefaultTopology()
+
+	queues := map[string]amqp.Table{}
+	for _, q := range topology.Queues {
+		queues[q.Name] = q.Arguments
+	}
+	exchanges := map[string]bool{}
+	for _, e := range topology.Exchanges {
+		exchanges[e.Name] = true
+	}
+	for _, b := range topology.Bindings {
+		assert.Contains(t, queues, b.Queue)
+		assert.True(t, exchanges[b.Exchange], b.Exchange)
+	}
+
+	// report_requests -> retry exchange -> retry queue -> reports exchange -> report_requests
+	assert.Equal(t, messaging.RetryExchange, queues[messaging.ReportRequestsQueue]["x-dead-letter-exchange"])
+	assert.Equal(t, messaging.ReportsExchange, queues[messaging.ReportRetryQueue]["x-dead-letter-exchange"])
+	assert.NotZero(t, queues[messaging.ReportRetryQueue]["x-message-ttl"])
+	assert.Contains(t, topology.Bindings, messaging.Binding{
+		Queue: messaging.ReportRequestsQueue, Exchange: messaging.ReportsExchange, RoutingKey: messaging.ReportRequestsQueue,
+	})
+	assert.Contains(t, queues, messaging.ReportStatusQueue)
+}
+
+func TestDefaultTopologyRetriesReportStatusUpdates(t *testing.T) {
+	topology := messaging.DefaultTopology()
+
+	queues := map[string]amqp.Table{}
+	for _, q := range topology.Queues {
+		queues[q.Name] = q.Arguments
+	}
+	retryQueue, deadLetterQueue := messaging.ReportStatusQueue+".retry", messaging.ReportStatusQueue+".dead"
+
+	// report_status -> consumer retry exchange -> report_status.retry -> default exchange -> report_status
+	assert.Equal(t, messaging.ConsumerRetryExchange, queues[messaging.ReportStatusQueue]["x-dead-letter-exchange"])
+	assert.Equal(t, messaging.ReportStatusQueue, queues[messaging.ReportStatusQueue]["x-dead-letter-routing-key"])
+	assert.Equal(t, "", queues[retryQueue]["x-dead-letter-exchange"])
+	assert.Equal(t, messaging.ReportStatusQueue, queues[retryQueue]["x-dead-letter-routing-key"])
+	assert.NotZero(t, queues[retryQueue]["x-message-ttl"])
+	assert.Contains(t, topology.Bindings, messaging.Binding{
+		Queue: retryQueue, Exchange: messaging.ConsumerRetryExchange, RoutingKey: messaging.ReportStatusQueue,
+	})
+	assert.Contains(t, topology.Bindings, messaging.Binding{
+		Queue: deadLetterQueue, Exchange: messaging.ConsumerDeadLetterExchange, RoutingKey: messaging.ReportStatusQueue,
+	})
+}
@@ -2,12 +2,16 @@ package unit
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tfgoztok/hotel-service/internal/domain"
 	"github.com/tfgoztok/hotel-service/internal/models"
 	"github.com/tfgoztok/hotel-service/internal/repository"
 )
@@ -26,18 +30,22 @@ func TestHotelRepositoryCreate(t *testing.T) {
 		OfficialName:    "John",
 		OfficialSurname: "Doe",
 		CompanyTitle:    "Test Hotel",
-		Location:        "New York",
+		Location:        "new york",
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 
-	mock.ExpectExec("INSERT INTO hotels").
-		WithArgs(hotel.ID, hotel.OfficialName, hotel.OfficialSurname, hotel.CompanyTitle, hotel.Location, hotel.CreatedAt, hotel.UpdatedAt).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	// The location is resolved by its folded name and the hotel takes over its canonical name
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO locations (.+) ON CONFLICT \\(normalized_name\\) (.+) INSERT INTO hotels").
+		WithArgs(hotel.ID, hotel.OfficialName, hotel.OfficialSurname, hotel.CompanyTitle, "new york", hotel.CreatedAt, hotel.UpdatedAt, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"location"}).AddRow("New York"))
+	mock.ExpectCommit()
 
-	err = repo.Create(context.Background(), hotel)
+	err = repo.Create(context.Background(), hotel, nil)
 
 	assert.NoError(t, err)
+	assert.Equal(t, "New York", hotel.Location)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -52,16 +60,47 @@ func TestHotelRepositoryDelete(t *testing.T) {
 
 	hotelID := uuid.New()
 
+	// The announcement is written in the transaction of the delete
+	mock.ExpectBegin()
 	mock.ExpectExec("DELETE FROM hotels").
 		WithArgs(hotelID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), "hotel.events", "hotel.deleted", "", []byte(`{}`), []byte(nil), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	err = repo.Delete(context.Background(), hotelID)
+	err = repo.Delete(context.Background(), hotelID, announceMessage(&models.OutboxMessage{
+		ID: uuid.New(), Exchange: "hotel.events", Destination: "hotel.deleted", Payload: []byte(`{}`),
+	}))
 
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestHotelRepositoryCreateRollsBackWithoutOutboxMessage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The hotel is not created if the event announcing it cannot be queued
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO hotels").WillReturnRows(sqlmock.NewRows([]string{"location"}).AddRow("Istanbul"))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	hotel := &models.Hotel{ID: uuid.New(), Location: "Istanbul"}
+	err = repository.NewHotelRepository(db).Create(context.Background(), hotel, announceMessage(&models.OutboxMessage{ID: uuid.New()}))
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// announceMessage returns an announcement of a fixed message.
+func announceMessage(msg *models.OutboxMessage) repository.Announcement {
+	return func(ctx context.Context) (*models.OutboxMessage, error) { return msg, nil }
+}
+
 func TestHotelRepositoryGetByID(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -77,12 +116,13 @@ func TestHotelRepositoryGetByID(t *testing.T) {
 		OfficialName:    "John",
 		OfficialSurname: "Doe",
 		CompanyTitle:    "Test Hotel",
+		Location:        "New York",
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "official_name", "official_surname", "company_title", "created_at", "updated_at"}).
-		AddRow(expectedHotel.ID, expectedHotel.OfficialName, expectedHotel.OfficialSurname, expectedHotel.CompanyTitle, expectedHotel.CreatedAt, expectedHotel.UpdatedAt)
+	rows := sqlmock.NewRows([]string{"id", "official_name", "official_surname", "company_title", "location", "latitude", "longitude", "created_at", "updated_at"}).
+		AddRow(expectedHotel.ID, expectedHotel.OfficialName, expectedHotel.OfficialSurname, expectedHotel.CompanyTitle, expectedHotel.Location, nil, nil, expectedHotel.CreatedAt, expectedHotel.UpdatedAt)
 
 	mock.ExpectQuery("SELECT (.+) FROM hotels").
 		WithArgs(hotelID).
@@ -95,6 +135,40 @@ func TestHotelRepositoryGetByID(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestHotelRepositoryUpdateVersionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewHotelRepository(db)
+
+	version := time.Now()
+	hotel := &models.Hotel{
+		ID:              uuid.New(),
+		OfficialName:    "John",
+		OfficialSurname: "Doe",
+		CompanyTitle:    "Test Hotel",
+		Location:        "New York",
+		UpdatedAt:       time.Now(),
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE hotels").
+		WithArgs(hotel.ID, hotel.OfficialName, hotel.OfficialSurname, hotel.CompanyTitle, hotel.Location, hotel.UpdatedAt, pq.Array([]string{version.UTC().Format(time.RFC3339Nano)}), nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(hotel.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	err = repo.Update(context.Background(), hotel, []time.Time{version}, nil)
+
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestContactRepositoryCreate(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -113,13 +187,116 @@ func TestContactRepositoryCreate(t *testing.T) {
 		UpdatedAt: time.Now(),
 	}
 
+	mock.ExpectBegin()
 	mock.ExpectExec("INSERT INTO contacts").
 		WithArgs(contact.ID, contact.HotelID, contact.Type, contact.Content, contact.CreatedAt, contact.UpdatedAt).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	err = repo.Create(context.Background(), contact, nil)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContactRepositoryCreateUnknownHotel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewContactRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO contacts").
+		WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectRollback()
+
+	err = repo.Create(context.Background(), &models.Contact{ID: uuid.New(), HotelID: uuid.New()}, nil)
+
+	assert.Equal(t, domain.KindNotFound, domain.KindOf(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHotelRepositoryGetByLocationMatchesFoldedName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewHotelRepository(db)
+
+	mock.ExpectQuery(`FROM hotels WHERE location_id = \(SELECT id FROM locations WHERE normalized_name = fold_location\(\$1\)\)`).
+		WithArgs("İSTANBUL").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "official_name", "official_surname", "company_title", "location", "latitude", "longitude", "created_at", "updated_at"}).
+			AddRow(uuid.New(), "John", "Doe", "Test Hotel", "Istanbul", nil, nil, time.Now(), time.Now()))
+
+	hotels, err := repo.GetByLocation(context.Background(), "İSTANBUL")
+
+	assert.NoError(t, err)
+	assert.Len(t, hotels, 1)
+	assert.Equal(t, "Istanbul", hotels[0].Location)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLocationRepositoryUpdateRenamesHotels(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewLocationRepository(db)
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	location := &models.Location{ID: uuid.New(), Name: "İstanbul", Country: "Türkiye", UpdatedAt: time.Now()}
+
+	hotelID := uuid.New()
+
+	// The renamed hotels get a new version and are announced in the same transaction
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE locations`).
+		WithArgs(location.ID, "İstanbul", "Türkiye", "", "", location.Latitude, location.Longitude, location.UpdatedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(created))
+	mock.ExpectQuery(`UPDATE hotels\s+SET location = \$2, updated_at = \$3`).
+		WithArgs(location.ID, "İstanbul", location.UpdatedAt).
+		WillReturnRows(sqlmock.NewRows(hotelColumns).AddRow(hotelID, "John", "Doe", "Hotel", "İstanbul", nil, nil, created, location.UpdatedAt))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), "hotel.events", "hotel.updated", "", []byte(`{}`), []byte(nil), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
-	err = repo.Create(context.Background(), contact)
+	var announced []*models.Hotel
+	err = repo.Update(context.Background(), location, func(hotel *models.Hotel) repository.Announcement {
+		announced = append(announced, hotel)
+		return announceMessage(&models.OutboxMessage{ID: uuid.New(), Exchange: "hotel.events", Destination: "hotel.updated", Payload: []byte(`{}`)})
+	})
 
 	assert.NoError(t, err)
+	assert.Equal(t, created, location.CreatedAt)
+	require.Len(t, announced, 1)
+	assert.Equal(t, hotelID, announced[0].ID)
+	assert.Equal(t, "İstanbul", announced[0].Location)
+	assert.Equal(t, location.UpdatedAt, announced[0].UpdatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLocationRepositoryDeleteInUse(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewLocationRepository(db)
+	id := uuid.New()
+
+	mock.ExpectExec("DELETE FROM locations").WithArgs(id).WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectExec("DELETE FROM locations").WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.Equal(t, domain.KindConflict, domain.KindOf(repo.Delete(context.Background(), id)))
+	assert.Equal(t, domain.KindNotFound, domain.KindOf(repo.Delete(context.Background(), id)))
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -132,13 +309,16 @@ func TestContactRepositoryDelete(t *testing.T) {
 
 	repo := repository.NewContactRepository(db)
 
+	hotelID := uuid.New()
 	contactID := uuid.New()
 
-	mock.ExpectExec("DELETE FROM contacts").
-		WithArgs(contactID).
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM contacts WHERE id = \\$1 AND hotel_id = \\$2").
+		WithArgs(contactID, hotelID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
-	err = repo.Delete(context.Background(), contactID)
+	err = repo.Delete(context.Background(), hotelID, contactID, nil)
 
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -188,3 +368,126 @@ func TestContactRepositoryGetByHotelID(t *testing.T) {
 	assert.Equal(t, expectedContacts, contacts)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestHotelRepositoryList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewHotelRepository(db)
+
+	after := &models.HotelCursor{CreatedAt: time.Now(), ID: uuid.New()}
+	expectedHotel := &models.Hotel{
+		ID:              uuid.New(),
+		OfficialName:    "John",
+		OfficialSurname: "Doe",
+		CompanyTitle:    "Test Hotel",
+		Location:        "New York",
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "official_name", "official_surname", "company_title", "location", "latitude", "longitude", "created_at", "updated_at"}).
+		AddRow(expectedHotel.ID, expectedHotel.OfficialName, expectedHotel.OfficialSurname, expectedHotel.CompanyTitle, expectedHotel.Location, nil, nil, expectedHotel.CreatedAt, expectedHotel.UpdatedAt)
+
+	mock.ExpectQuery(`SELECT (.+) FROM hotels WHERE location_id = \(SELECT id FROM locations WHERE normalized_name = fold_location\(\$1\)\) AND company_title ILIKE \$2 (.+) AND \(created_at, id\) < \(\$3, \$4\) ORDER BY created_at DESC, id DESC LIMIT \$5`).
+		WithArgs("New York", `Test\_%`, after.CreatedAt, after.ID, 10).
+		WillReturnRows(rows)
+
+	hotels, err := repo.List(context.Background(), models.HotelFilter{
+		Location:           "New York",
+		CompanyTitlePrefix: "Test_",
+		Descending:         true,
+		After:              after,
+		Limit:              10,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.Hotel{expectedHotel}, hotels)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContactRepositoryGetByHotelIDFiltersByType(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewContactRepository(db)
+
+	hotelID := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM contacts WHERE hotel_id = \$1 AND type = ANY\(\$2\)`).
+		WithArgs(hotelID, pq.StringArray{"PHONE", "FAX"}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hotel_id", "type", "content", "created_at", "updated_at"}))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(hotelID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	contacts, err := repo.GetByHotelID(context.Background(), hotelID, models.ContactTypePhone, models.ContactTypeFax)
+
+	assert.NoError(t, err)
+	assert.Empty(t, contacts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContactRepositoryGetByHotelIDsGroupsByHotel(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewContactRepository(db)
+
+	first, second := uuid.New(), uuid.New()
+	now := time.Now()
+	mock.ExpectQuery(`SELECT (.+) FROM contacts WHERE hotel_id = ANY\(\$1::uuid\[\]\)`).
+		WithArgs(pq.StringArray{first.String(), second.String()}).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hotel_id", "type", "content", "created_at", "updated_at"}).
+			AddRow(uuid.New(), first, "EMAIL", "info@first.example", now, now).
+			AddRow(uuid.New(), second, "PHONE", "+90 212 555 0000", now, now).
+			AddRow(uuid.New(), first, "PHONE", "+90 212 555 0001", now, now))
+
+	contacts, err := repo.GetByHotelIDs(context.Background(), []uuid.UUID{first, second})
+
+	assert.NoError(t, err)
+	assert.Len(t, contacts[first], 2)
+	assert.Len(t, contacts[second], 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHotelRepositorySearchRanksMatchesAndCountsLocations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewHotelRepository(db)
+
+	hotelID := uuid.New()
+	now := time.Now()
+	mock.ExpectQuery(`SELECT (.+) ts_rank_cd\(search_vector, query\) AS score FROM hotels, websearch_to_tsquery\('simple'::regconfig, fold_location\(\$1\)\) query WHERE search_vector @@ query AND location_id = \(SELECT id FROM locations WHERE normalized_name = fold_location\(\$4\)\) ORDER BY score DESC, id LIMIT \$2 OFFSET \$3`).
+		WithArgs("grand hotel", 10, 20, "istanbul").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "official_name", "official_surname", "company_title", "location", "latitude", "longitude", "created_at", "updated_at", "score"}).
+			AddRow(hotelID, "John", "Doe", "Grand Hotel", "İstanbul", nil, nil, now, now, 0.6))
+	mock.ExpectQuery(`SELECT location, COUNT\(\*\) AS count, SUM\(COUNT\(\*\) FILTER \(WHERE location_id = \(SELECT id FROM locations WHERE normalized_name = fold_location\(\$3\)\)\)\) OVER \(\) AS total (.+) GROUP BY location ORDER BY count DESC, location LIMIT \$2`).
+		WithArgs("grand hotel", models.LocationFacetSize, "istanbul").
+		WillReturnRows(sqlmock.NewRows([]string{"location", "count", "total"}).
+			AddRow("İstanbul", 21, 21).
+			AddRow("Ankara", 4, 21))
+
+	result, err := repo.Search(context.Background(), models.HotelSearchQuery{Text: "grand hotel", Location: "istanbul", Limit: 10, Offset: 20})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(21), result.Total)
+	if assert.Len(t, result.Hits, 1) {
+		assert.Equal(t, hotelID, result.Hits[0].ID)
+		assert.Equal(t, 0.6, result.Hits[0].Score)
+	}
+	assert.Equal(t, []models.FacetCount{{Value: "İstanbul", Count: 21}, {Value: "Ankara", Count: 4}}, result.Facets["location"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
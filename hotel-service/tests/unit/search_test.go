@@ -0,0 +1,251 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/olivere/elastic/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/search"
+	"github.com/tfgoztok/hotel-service/internal/service"
+)
+
+// esRequest is a request received by the fake Elasticsearch server.
+type esRequest struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// newFakeElasticsearch starts a server answering every request with respond and returns a
+// client for it along with the requests received so far.
+func newFakeElasticsearch(t *testing.T, respond func(req esRequest) (int, string)) (*elastic.Client, func() []esRequest) {
+	var (
+		mu       sync.Mutex
+		requests []esRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := esRequest{Method: r.Method, Path: r.URL.Path, Body: string(body)}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+
+		status, response := respond(req)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := elastic.NewClient(elastic.SetURL(srv.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	require.NoError(t, err)
+	return client, func() []esRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]esRequest(nil), requests...)
+	}
+}
+
+func TestIndexSearchBuildsFuzzyQueryAndParsesResult(t *testing.T) {
+	hotelID := uuid.New()
+	client, requests := newFakeElasticsearch(t, func(req esRequest) (int, string) {
+		return http.StatusOK, `{
+			"hits": {
+				"total": {"value": 1, "relation": "eq"},
+				"hits": [{
+					"_id": "` + hotelID.String() + `",
+					"_score": 2.5,
+					"_source": {"id": "` + hotelID.String() + `", "company_title": "Grand Hotel", "location": "İstanbul", "contacts": ["info@grand.example"]},
+					"highlight": {"company_title": ["<em>Grand</em> Hotel"]}
+				}]
+			},
+			"aggregations": {
+				"location": {"buckets": [{"key": "İstanbul", "doc_count": 3}, {"key": "Ankara", "doc_count": 1}]}
+			}
+		}`
+	})
+
+	result, err := search.NewIndex(client).Search(context.Background(), models.HotelSearchQuery{
+		Text: "grnd", Location: "İstanbul", Limit: 10, Offset: 20,
+	})
+	require.NoError(t, err)
+
+	reqs := requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "/"+search.HotelsAlias+"/_search", reqs[0].Path)
+	var body struct {
+		From       int                        `json:"from"`
+		Size       int                        `json:"size"`
+		Query      map[string]json.RawMessage `json:"query"`
+		PostFilter map[string]json.RawMessage `json:"post_filter"`
+		Aggs       map[string]json.RawMessage `json:"aggregations"`
+		Highlight  json.RawMessage            `json:"highlight"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(reqs[0].Body), &body))
+	assert.Equal(t, 20, body.From)
+	assert.Equal(t, 10, body.Size)
+	assert.Contains(t, string(body.Query["multi_match"]), `"fuzziness":"AUTO"`)
+	assert.Contains(t, string(body.PostFilter["term"]), `"location.keyword":"İstanbul"`)
+	assert.Contains(t, string(body.Aggs["location"]), `"field":"location.keyword"`)
+	assert.NotEmpty(t, body.Highlight)
+
+	assert.Equal(t, int64(1), result.Total)
+	require.Len(t, result.Hits, 1)
+	assert.Equal(t, hotelID, result.Hits[0].ID)
+	assert.Equal(t, "Grand Hotel", result.Hits[0].CompanyTitle)
+	assert.Equal(t, 2.5, result.Hits[0].Score)
+	assert.Equal(t, []string{"<em>Grand</em> Hotel"}, result.Hits[0].Highlights["company_title"])
+	assert.Equal(t, []models.FacetCount{{Value: "İstanbul", Count: 3}, {Value: "Ankara", Count: 1}}, result.Facets["location"])
+}
+
+func TestIndexSearchReportsUnreachableClusterAsUnavailable(t *testing.T) {
+	client, _ := newFakeElasticsearch(t, func(req esRequest) (int, string) {
+		return http.StatusServiceUnavailable, `{"error": {"type": "cluster_block_exception"}, "status": 503}`
+	})
+
+	_, err := search.NewIndex(client).Search(context.Background(), models.HotelSearchQuery{Text: "grand", Limit: 10})
+	assert.Equal(t, domain.KindUnavailable, domain.KindOf(err))
+}
+
+// stubHotelLoader returns a fixed hotel, or a not-found error when hotel is nil, along with
+// fixed officials.
+type stubHotelLoader struct {
+	hotel     *models.Hotel
+	contacts  []*models.Contact
+	officials []*models.Official
+}
+
+func (s stubHotelLoader) GetByIDWithContacts(ctx context.Context, id uuid.UUID) (*models.Hotel, []*models.Contact, error) {
+	if s.hotel == nil {
+		return nil, nil, domain.NotFound("hotel")
+	}
+	return s.hotel, s.contacts, nil
+}
+
+func (s stubHotelLoader) GetByHotelID(ctx context.Context, hotelID uuid.UUID) ([]*models.Official, error) {
+	return s.officials, nil
+}
+
+func TestIndexerReindexesHotelOfContactEvent(t *testing.T) {
+	hotel := &models.Hotel{ID: uuid.New(), CompanyTitle: "Grand Hotel", Location: "Ankara"}
+	client, requests := newFakeElasticsearch(t, func(req esRequest) (int, string) {
+		return http.StatusOK, `{"result": "updated"}`
+	})
+	loader := stubHotelLoader{
+		hotel:    hotel,
+		contacts: []*models.Contact{{Type: models.ContactTypeEmail, Content: "info@grand.example"}},
+	}
+	indexer := search.NewIndexer(search.NewIndex(client), loader, loader, nil)
+
+	event, err := events.New(context.Background(), events.ContactAdded, &models.Contact{
+		ID: uuid.New(), HotelID: hotel.ID, Type: models.ContactTypeEmail, Content: "info@grand.example",
+	})
+	require.NoError(t, err)
+	require.NoError(t, indexer.Publish(context.Background(), event))
+
+	reqs := requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, http.MethodPut, reqs[0].Method)
+	assert.Equal(t, "/"+search.HotelsAlias+"/_doc/"+hotel.ID.String(), reqs[0].Path)
+	assert.Contains(t, reqs[0].Body, `"contacts":["info@grand.example"]`)
+}
+
+func TestIndexerIndexesAllOfficialsOfHotelOfOfficialEvent(t *testing.T) {
+	hotel := &models.Hotel{ID: uuid.New(), OfficialName: "John", OfficialSurname: "Doe", CompanyTitle: "Grand Hotel", Location: "Ankara"}
+	client, requests := newFakeElasticsearch(t, func(req esRequest) (int, string) {
+		return http.StatusOK, `{"result": "updated"}`
+	})
+	loader := stubHotelLoader{
+		hotel: hotel,
+		officials: []*models.Official{
+			{Name: "John", Surname: "Doe", Role: models.OfficialRolePrimary},
+			{Name: "Ayşe", Surname: "Yılmaz", Role: models.OfficialRoleOwner},
+		},
+	}
+	indexer := search.NewIndexer(search.NewIndex(client), loader, loader, nil)
+
+	event, err := events.New(context.Background(), events.OfficialRemoved, events.OfficialRemovedPayload{ID: uuid.New(), HotelID: hotel.ID})
+	require.NoError(t, err)
+	require.NoError(t, indexer.Publish(context.Background(), event))
+
+	reqs := requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, "/"+search.HotelsAlias+"/_doc/"+hotel.ID.String(), reqs[0].Path)
+	assert.Contains(t, reqs[0].Body, `"officials":["John Doe","Ayşe Yılmaz"]`)
+}
+
+func TestIndexerDeletesDocumentOfDeletedHotel(t *testing.T) {
+	hotelID := uuid.New()
+	client, requests := newFakeElasticsearch(t, func(req esRequest) (int, string) {
+		return http.StatusNotFound, `{"result": "not_found"}`
+	})
+	indexer := search.NewIndexer(search.NewIndex(client), stubHotelLoader{}, stubHotelLoader{}, nil)
+
+	event, err := events.New(context.Background(), events.HotelDeleted, events.HotelDeletedPayload{ID: hotelID})
+	require.NoError(t, err)
+	require.NoError(t, indexer.Publish(context.Background(), event)) // Not indexed yet is fine
+
+	reqs := requests()
+	require.Len(t, reqs, 1)
+	assert.Equal(t, http.MethodDelete, reqs[0].Method)
+	assert.Equal(t, "/"+search.HotelsAlias+"/_doc/"+hotelID.String(), reqs[0].Path)
+}
+
+func TestSearchHotelsValidatesQueryAndAvailability(t *testing.T) {
+	router, mock := newTestRouter(t) // No search backend
+
+	cases := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"missing text", "", http.StatusUnprocessableEntity},
+		{"invalid limit", "q=grand&limit=0", http.StatusBadRequest},
+		{"invalid offset", "q=grand&offset=-1", http.StatusBadRequest},
+		{"window too deep", "q=grand&offset=9990&limit=20", http.StatusUnprocessableEntity},
+		{"index unavailable", "q=grand", http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/hotels/search?"+tc.query, nil))
+			assert.Equal(t, tc.status, rec.Code, rec.Body.String())
+			assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "application/problem+json"))
+		})
+	}
+	require.NoError(t, mock.ExpectationsWereMet()) // Not routed to /hotels/{id}
+}
+
+// recordingSearcher is a HotelSearcher that records the queries it receives.
+type recordingSearcher struct {
+	queries []models.HotelSearchQuery
+}
+
+func (s *recordingSearcher) Search(ctx context.Context, query models.HotelSearchQuery) (*models.HotelSearchResult, error) {
+	s.queries = append(s.queries, query)
+	return &models.HotelSearchResult{Hits: []*models.HotelSearchHit{}}, nil
+}
+
+func TestSearchServiceTrimsTextAndClampsLimitForBackend(t *testing.T) {
+	backend := &recordingSearcher{}
+	svc := service.NewSearchService(backend)
+
+	_, err := svc.SearchHotels(context.Background(), models.HotelSearchQuery{Text: "  grand  ", Limit: 1000})
+	require.NoError(t, err)
+
+	require.Len(t, backend.queries, 1)
+	assert.Equal(t, "grand", backend.queries[0].Text)
+	assert.Equal(t, service.MaxPageSize, backend.queries[0].Limit)
+}
@@ -1,8 +1,516 @@
 package unit
 
 import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tfgoztok/hotel-service/internal/domain"
+	"github.com/tfgoztok/hotel-service/internal/events"
+	"github.com/tfgoztok/hotel-service/internal/messaging"
+	"github.com/tfgoztok/hotel-service/internal/models"
+	"github.com/tfgoztok/hotel-service/internal/repository"
+	"github.com/tfgoztok/hotel-service/internal/service"
+)
+
+var (
+	hotelColumns    = []string{"id", "official_name", "official_surname", "company_title", "location", "latitude", "longitude", "created_at", "updated_at"}
+	officialColumns = []string{"id", "hotel_id", "name", "surname", "role", "title", "active_from", "active_to", "created_at", "updated_at"}
 )
 
-func TestServices(t *testing.T) {
+func TestHotelServiceListHotelsPaginates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(hotelColumns)
+	for i := 0; i < 3; i++ {
+		rows.AddRow(uuid.New(), "John", "Doe", "Hotel", "Istanbul", nil, nil, base.Add(time.Duration(i)*time.Hour), base)
+	}
+	// The service asks for one row more than the page size to detect the next page
+	mock.ExpectQuery("SELECT (.+) FROM hotels").WithArgs(3).WillReturnRows(rows)
+
+	page, err := hotelService.ListHotels(context.Background(), models.HotelFilter{Limit: 2}, "")
+	require.NoError(t, err)
+	assert.Len(t, page.Hotels, 2)
+	require.NotEmpty(t, page.NextCursor)
+
+	last := page.Hotels[1]
+	mock.ExpectQuery(`SELECT (.+) FROM hotels WHERE \(created_at, id\) > \(\$1, \$2\)`).
+		WithArgs(last.CreatedAt, last.ID, 3).
+		WillReturnRows(sqlmock.NewRows(hotelColumns))
+
+	page, err = hotelService.ListHotels(context.Background(), models.HotelFilter{Limit: 2}, page.NextCursor)
+	require.NoError(t, err)
+	assert.Empty(t, page.Hotels)
+	assert.Empty(t, page.NextCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHotelServiceListHotelsRejectsInvalidCursor(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	_, err = hotelService.ListHotels(context.Background(), models.HotelFilter{}, "not-a-cursor")
+	assert.ErrorIs(t, err, service.ErrInvalidCursor)
+}
+
+func TestHotelServiceListHotelsRejectsCursorOfAnotherSort(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows(hotelColumns).
+		AddRow(uuid.New(), "John", "Doe", "Hotel", "Istanbul", nil, nil, base.Add(time.Hour), base).
+		AddRow(uuid.New(), "Jane", "Doe", "Hotel", "Istanbul", nil, nil, base, base)
+	mock.ExpectQuery("SELECT (.+) FROM hotels").WithArgs(2).WillReturnRows(rows)
+
+	page, err := hotelService.ListHotels(context.Background(), models.HotelFilter{Descending: true, Limit: 1}, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	_, err = hotelService.ListHotels(context.Background(), models.HotelFilter{Limit: 1}, page.NextCursor)
+	assert.ErrorIs(t, err, service.ErrCursorSortMismatch)
+	assert.Equal(t, domain.KindBadRequest, domain.KindOf(err)) // Reported as 400, like ErrInvalidCursor
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHotelServicePatchHotelMergesFields(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	id := uuid.New()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	version := created.Add(time.Hour)
+	mock.ExpectQuery("SELECT (.+) FROM hotels").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(hotelColumns).AddRow(id, "John", "Doe", "Old Title", "Istanbul", nil, nil, created, version))
+	// The read version guards the update when the caller sent no If-Match
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE hotels").
+		WithArgs(id, "John", "Doe", "New Title", "Istanbul", sqlmock.AnyArg(), pq.Array([]string{version.Format(time.RFC3339Nano)}), nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"location", "created_at", "updated_at"}).AddRow("Istanbul", created, version.Add(time.Minute)))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	hotel, err := hotelService.PatchHotel(context.Background(), id, []byte(`{"company_title":"New Title","id":"`+uuid.New().String()+`"}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, id, hotel.ID)
+	assert.Equal(t, "New Title", hotel.CompanyTitle)
+	assert.Equal(t, "Istanbul", hotel.Location)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContactServiceAddContactNormalizesContent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	contactService := service.NewContactService(repository.NewContactRepository(db))
+
+	contact := &models.Contact{HotelID: uuid.New(), Type: " phone ", Content: "+90 (212) 123-45-67"}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO contacts").
+		WithArgs(sqlmock.AnyArg(), contact.HotelID, "PHONE", "+902121234567", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, contactService.AddContact(context.Background(), contact))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContactServiceAddContactRejectsInvalidContent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	contactService := service.NewContactService(repository.NewContactRepository(db))
+
+	tests := map[string]models.Contact{
+		"phone":    {Type: "PHONE", Content: "12345"},
+		"email":    {Type: "EMAIL", Content: "John <john@example.com>"},
+		"website":  {Type: "WEBSITE", Content: "example.com"},
+		"location": {Type: "LOCATION", Content: "91,29"},
+		"unknown":  {Type: "PAGER", Content: "555"},
+	}
+	for name, contact := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := contactService.AddContact(context.Background(), &contact)
+
+			var domainErr *domain.Error
+			require.ErrorAs(t, err, &domainErr)
+			assert.Equal(t, domain.KindValidation, domainErr.Kind)
+			assert.Len(t, domainErr.Fields, 1)
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet()) // Nothing reaches the database
+}
+
+func TestHotelServiceCreateHotelReportsAllInvalidFields(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	err = hotelService.CreateHotel(context.Background(), &models.Hotel{
+		OfficialName:    "  ",
+		OfficialSurname: "Doe",
+		CompanyTitle:    "Test Hotel",
+		Location:        strings.Repeat("x", 101),
+	})
+
+	var domainErr *domain.Error
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, []domain.FieldError{
+		{Field: "official_name", Message: "must not be empty"},
+		{Field: "location", Message: "must be at most 100 characters"},
+	}, domainErr.Fields)
+}
+
+func TestHotelServiceGetHotelDetailsGroupsContacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	id := uuid.New()
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	columns := append(append([]string{}, hotelColumns...), "id", "type", "content", "created_at", "updated_at")
+	mock.ExpectQuery("SELECT (.+) FROM hotels h LEFT JOIN contacts c").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(id, "John", "Doe", "Hotel", "Istanbul", nil, nil, created, created, uuid.New(), "PHONE", "+902121234567", created, created).
+			AddRow(id, "John", "Doe", "Hotel", "Istanbul", nil, nil, created, created, uuid.New(), "PHONE", "+902121234568", created, created).
+			AddRow(id, "John", "Doe", "Hotel", "Istanbul", nil, nil, created, created, uuid.New(), "EMAIL", "info@example.com", created, created))
+
+	mock.ExpectQuery("SELECT (.+) FROM officials").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(officialColumns).
+			AddRow(uuid.New(), id, "John", "Doe", "PRIMARY", "", nil, nil, created, created).
+			AddRow(uuid.New(), id, "Jane", "Roe", "SALES_DIRECTOR", "Director of Sales", created, nil, created, created))
+
+	details, err := hotelService.GetHotelDetails(context.Background(), id, models.HotelIncludes{Contacts: true, Officials: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Istanbul", details.Location)
+	assert.Len(t, details.Contacts[models.ContactTypePhone], 2)
+	assert.Len(t, details.Contacts[models.ContactTypeEmail], 1)
+	require.Len(t, details.Officials, 2)
+	assert.Equal(t, models.OfficialRoleSalesDirector, details.Officials[1].Role)
+	assert.Equal(t, created, details.Officials[1].ActiveFrom.Time)
+	assert.Nil(t, details.Officials[1].ActiveTo)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHotelServiceGetHotelDetailsWithoutContacts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	id := uuid.New()
+	columns := append(append([]string{}, hotelColumns...), "id", "type", "content", "created_at", "updated_at")
+	mock.ExpectQuery("SELECT (.+) FROM hotels h LEFT JOIN contacts c").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(id, "John", "Doe", "Hotel", "Istanbul", nil, nil, time.Now(), time.Now(), nil, nil, nil, nil, nil))
+
+	details, err := hotelService.GetHotelDetails(context.Background(), id, models.HotelIncludes{Contacts: true})
+	require.NoError(t, err)
+	assert.Empty(t, details.Contacts)
+	assert.Nil(t, details.Officials)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOfficialServiceRemoveOfficialKeepsPrimary(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	officialService := service.NewOfficialService(repository.NewOfficialRepository(db))
+
+	hotelID, officialID := uuid.New(), uuid.New()
+	mock.ExpectQuery("SELECT (.+) FROM officials").
+		WithArgs(officialID, hotelID).
+		WillReturnRows(sqlmock.NewRows(officialColumns).
+			AddRow(officialID, hotelID, "John", "Doe", "PRIMARY", "", nil, nil, time.Now(), time.Now()))
+
+	err = officialService.RemoveOfficial(context.Background(), hotelID, officialID)
+
+	assert.Equal(t, domain.KindConflict, domain.KindOf(err))
+	assert.NoError(t, mock.ExpectationsWereMet()) // No DELETE was issued
+}
+
+func TestOfficialServiceRemoveOfficialAnnouncesRemoval(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	officialService := service.NewOfficialService(repository.NewOfficialRepository(db))
+
+	// The search index picks up the removal through the event queued with it
+	hotelID, officialID := uuid.New(), uuid.New()
+	payload := &payloadCapture{}
+	mock.ExpectQuery("SELECT (.+) FROM officials").
+		WithArgs(officialID, hotelID).
+		WillReturnRows(sqlmock.NewRows(officialColumns).
+			AddRow(officialID, hotelID, "Jane", "Roe", "OWNER", "", nil, nil, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM officials").WithArgs(officialID, hotelID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), messaging.HotelEventsExchange, "official.removed", "", payload, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, officialService.RemoveOfficial(context.Background(), hotelID, officialID))
+	require.NoError(t, events.Validate(payload.value))
+	assert.JSONEq(t, `{"id":"`+officialID.String()+`","hotel_id":"`+hotelID.String()+`"}`, string(mustField(t, payload.value, "payload")))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOfficialServiceAddOfficialValidatesActivePeriod(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	officialService := service.NewOfficialService(repository.NewOfficialRepository(db))
+
+	from := models.Date{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}
+	to := models.Date{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	err = officialService.AddOfficial(context.Background(), &models.Official{
+		HotelID:    uuid.New(),
+		Name:       "Jane",
+		Surname:    "Roe",
+		Role:       "owner",
+		ActiveFrom: &from,
+		ActiveTo:   &to,
+	})
+
+	var domainErr *domain.Error
+	require.ErrorAs(t, err, &domainErr)
+	assert.Equal(t, []domain.FieldError{{Field: "active_to", Message: "must not be before active_from"}}, domainErr.Fields)
+}
+
+func TestLocationServiceCreateLocationValidatesCoordinates(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	locationService := service.NewLocationService(repository.NewLocationRepository(db))
+
+	latitude, longitude := 41.01, 190.0
+	for _, tc := range []struct {
+		location models.Location
+		fields   []domain.FieldError
+	}{
+		{
+			location: models.Location{Name: " ", Latitude: &latitude},
+			fields: []domain.FieldError{
+				{Field: "name", Message: "must not be empty"},
+				{Field: "latitude", Message: "latitude and longitude must be given together"},
+			},
+		},
+		{
+			location: models.Location{Name: "Istanbul", Latitude: &latitude, Longitude: &longitude},
+			fields:   []domain.FieldError{{Field: "longitude", Message: "must be in [-180, 180]"}},
+		},
+	} {
+		err := locationService.CreateLocation(context.Background(), &tc.location)
+
+		var domainErr *domain.Error
+		require.ErrorAs(t, err, &domainErr)
+		assert.Equal(t, tc.fields, domainErr.Fields)
+	}
+}
+
+// payloadCapture is a sqlmock argument matcher that keeps the value it matched.
+type payloadCapture struct{ value []byte }
+
+func (c *payloadCapture) Match(v driver.Value) bool {
+	c.value, _ = v.([]byte)
+	return true
+}
+
+func TestReportServiceQueuesVersionedEnvelope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	payload := &payloadCapture{}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO report_requests").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), "", "report_requests", sqlmock.AnyArg(), payload, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	reportService := service.NewReportService(repository.NewReportRepository(db), repository.NewHotelRepository(db))
+	request, err := reportService.RequestReport(context.Background(), "  Istanbul ")
+	require.NoError(t, err)
+	assert.Equal(t, "Istanbul", request.Location)
+
+	// The queued message is a valid report.requested v1 event wrapping the request
+	require.NoError(t, events.Validate(payload.value))
+	var envelope events.Envelope
+	require.NoError(t, json.Unmarshal(payload.value, &envelope))
+	assert.Equal(t, events.ReportRequested.Name, envelope.Type)
+	assert.Equal(t, events.ReportRequested.Version, envelope.Version)
+	assert.JSONEq(t, `"`+request.ID.String()+`"`, string(mustField(t, envelope.Payload, "id")))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// mustField returns a top-level field of a JSON object.
+func mustField(t *testing.T, data []byte, field string) json.RawMessage {
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	return fields[field]
+}
+
+func TestHotelServiceEmitsEventsForCommittedChanges(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	// The event is queued in the transaction of the change, with the canonical location
+	hotel := &models.Hotel{OfficialName: "John", OfficialSurname: "Doe", CompanyTitle: "Test Hotel", Location: "istanbul"}
+	payload := &payloadCapture{}
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO hotels").WillReturnRows(sqlmock.NewRows([]string{"location"}).AddRow("Istanbul"))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), messaging.HotelEventsExchange, "hotel.created", "", payload, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, hotelService.CreateHotel(context.Background(), hotel))
+	require.NoError(t, events.Validate(payload.value))
+	var queued events.Envelope
+	require.NoError(t, json.Unmarshal(payload.value, &queued))
+	assert.JSONEq(t, `"Istanbul"`, string(mustField(t, queued.Payload, "location")))
+
+	// A failed delete announces nothing
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM hotels").WithArgs(hotel.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	assert.True(t, domain.IsNotFound(hotelService.DeleteHotel(context.Background(), hotel.ID)))
+
+	deleted := &payloadCapture{}
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM hotels").WithArgs(hotel.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), messaging.HotelEventsExchange, "hotel.deleted", "", deleted, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, hotelService.DeleteHotel(context.Background(), hotel.ID))
+	require.NoError(t, events.Validate(deleted.value))
+	assert.JSONEq(t, `{"id":"`+hotel.ID.String()+`"}`, string(mustField(t, deleted.value, "payload")))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHotelServiceRejectsChangeWhoseEventCannotBeQueued(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	hotelService := service.NewHotelService(repository.NewHotelRepository(db), repository.NewOfficialRepository(db))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO hotels").WillReturnRows(sqlmock.NewRows([]string{"location"}).AddRow("Istanbul"))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnError(errors.New("disk full"))
+	mock.ExpectRollback()
+
+	hotel := &models.Hotel{OfficialName: "John", OfficialSurname: "Doe", CompanyTitle: "Test Hotel", Location: "Istanbul"}
+	assert.Error(t, hotelService.CreateHotel(context.Background(), hotel))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContactServiceEmitsContactRemoved(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	contactService := service.NewContactService(repository.NewContactRepository(db))
+
+	hotelID, contactID := uuid.New(), uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM contacts").WithArgs(contactID, hotelID).WillReturnResult(sqlmock.NewResult(0, 1))
+	payload := &payloadCapture{}
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), messaging.HotelEventsExchange, "contact.removed", "", payload, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, contactService.DeleteContact(context.Background(), hotelID, contactID))
+
+	require.NoError(t, events.Validate(payload.value))
+	assert.JSONEq(t, `{"id":"`+contactID.String()+`","hotel_id":"`+hotelID.String()+`"}`, string(mustField(t, payload.value, "payload")))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContactServicePatchContactEmitsContactUpdated(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	contactService := service.NewContactService(repository.NewContactRepository(db))
+
+	hotelID, contactID := uuid.New(), uuid.New()
+	createdAt := time.Now().UTC().Add(-time.Hour)
+	mock.ExpectQuery("SELECT (.+) FROM contacts").WithArgs(contactID, hotelID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hotel_id", "type", "content", "created_at", "updated_at"}).
+			AddRow(contactID, hotelID, "PHONE", "+902121234567", createdAt, createdAt))
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE contacts").
+		WithArgs(contactID, hotelID, models.ContactTypePhone, "+902129876543", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+	payload := &payloadCapture{}
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(sqlmock.AnyArg(), messaging.HotelEventsExchange, "contact.updated", "", payload, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, err = contactService.PatchContact(context.Background(), hotelID, contactID, []byte(`{"content":"+902129876543"}`))
+	require.NoError(t, err)
+
+	require.NoError(t, events.Validate(payload.value))
+	var contact models.Contact
+	require.NoError(t, json.Unmarshal(mustField(t, payload.value, "payload"), &contact))
+	assert.Equal(t, contactID, contact.ID)
+	assert.Equal(t, "+902129876543", contact.Content)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventMessageRoutesEventsByType(t *testing.T) {
+	event, err := events.New(context.Background(), events.HotelDeleted, events.HotelDeletedPayload{ID: uuid.New()})
+	require.NoError(t, err)
+
+	msg, err := messaging.EventMessage(event)
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, msg.ID)
+	assert.Equal(t, messaging.HotelEventsExchange, msg.Exchange)
+	assert.Equal(t, "hotel.deleted", msg.Destination)
+	assert.NoError(t, events.Validate(msg.Payload))
 }
@@ -20,6 +20,12 @@ namespace ReportService.Data.Repositories
             return await _reports.Find(r => r.Id == id).FirstOrDefaultAsync();
         }
 
+        // Asynchronously retrieves the report generated for a report request, if any
+        public async Task<Report?> GetByReportRequestIdAsync(Guid reportRequestId)
+        {
+            return await _reports.Find(r => r.ReportRequestId == reportRequestId).FirstOrDefaultAsync();
+        }
+
         // Asynchronously retrieves all reports
         public async Task<IEnumerable<Report>> GetAllAsync()
         {
@@ -1,6 +1,7 @@
 public interface IRabbitMQService
 {
-    void StartConsuming(Action<string> processMessage);
+    void StartConsuming(Func<string, bool, Task> processMessage);
     void Dispose();
     void PublishMessage(string message);
+    void PublishStatus(Guid reportRequestId, string status);
 }
\ No newline at end of file
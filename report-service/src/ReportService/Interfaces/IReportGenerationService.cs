@@ -3,8 +3,10 @@ namespace ReportService.Interfaces
     public interface IReportGenerationService
     {
         /// <summary>
-        /// Generates a report based on the provided message.
+        /// Generates a report based on the provided message. Unless this is the final attempt,
+        /// failures that may pass, such as an unreachable hotel-service, are thrown so the message
+        /// is delivered again; on the final attempt the report is marked as failed instead.
         /// </summary>
-        Task GenerateReport(string message);
+        Task GenerateReport(string message, bool finalAttempt = true);
     }
 }
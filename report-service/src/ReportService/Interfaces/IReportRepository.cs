@@ -6,6 +6,7 @@ namespace ReportService.Interfaces
     {
         // This interface defines the contract for report repository operations
         Task<Report> GetByIdAsync(string  id);
+        Task<Report?> GetByReportRequestIdAsync(Guid reportRequestId);
         Task<IEnumerable<Report>> GetAllAsync();
         Task AddAsync(Report report);
         Task UpdateAsync(Report report);
@@ -5,6 +5,9 @@ using ReportService.Services;
 using Serilog;
 using Serilog.Sinks.Elasticsearch;
 using Microsoft.Extensions.Logging;
+using OpenTelemetry.Exporter;
+using OpenTelemetry.Resources;
+using OpenTelemetry.Trace;
 using RabbitMQ.Client;
 
 var builder = WebApplication.CreateBuilder(args);
@@ -22,6 +25,25 @@ Log.Logger = new LoggerConfiguration()
 
 builder.Host.UseSerilog(); // Use Serilog for logging in the application
 
+// Configure tracing. The spans of processed report requests continue the traces of hotel-service and are
+// exported to the OTLP/HTTP collector in OTEL_EXPORTER_OTLP_ENDPOINT, e.g. http://otel-collector:4318, if set.
+// They are recorded without it too, so the GraphQL queries still carry the trace back to hotel-service.
+var otlpEndpoint = builder.Configuration["OTEL_EXPORTER_OTLP_ENDPOINT"];
+builder.Services.AddOpenTelemetry()
+    .ConfigureResource(resource => resource.AddService(builder.Configuration["OTEL_SERVICE_NAME"] ?? "report-service"))
+    .WithTracing(tracing =>
+    {
+        tracing.AddSource(RabbitMQService.ActivitySourceName); // Spans of processed report requests
+        if (!string.IsNullOrEmpty(otlpEndpoint))
+        {
+            tracing.AddOtlpExporter(options =>
+            {
+                options.Protocol = OtlpExportProtocol.HttpProtobuf; // Same collector endpoint as hotel-service
+                options.Endpoint = new Uri(otlpEndpoint.TrimEnd('/') + "/v1/traces");
+            });
+        }
+    });
+
 // Add services to the container.
 builder.Services.AddControllers(); // Add MVC controllers
 builder.Services.AddEndpointsApiExplorer(); // Add support for API endpoint exploration
@@ -36,7 +58,7 @@ builder.Services.AddSingleton<IMongoDatabase>(mongoDatabase); // Register MongoD
 
 // Add services
 builder.Services.AddSingleton<IConnectionFactory>(sp => 
-    new ConnectionFactory { HostName = builder.Configuration["RabbitMQ:HostName"] });
+    new ConnectionFactory { HostName = builder.Configuration["RabbitMQ:HostName"], DispatchConsumersAsync = true }); // Consumers await report generation
 builder.Services.AddSingleton<IRabbitMQService, RabbitMQService>(); // Register RabbitMQ service as a singleton
 builder.Services.AddScoped<IReportRepository, ReportRepository>(); // Register report repository with scoped lifetime
 builder.Services.AddScoped<IReportGenerationService, ReportGenerationService>(); // Register report generation service with scoped lifetime
@@ -71,25 +93,18 @@ app.UseHttpsRedirection(); // Redirect HTTP requests to HTTPS
 app.UseAuthorization(); // Enable authorization middleware
 app.MapControllers(); // Map controller routes
 
-// Start consuming RabbitMQ messages
+// Start consuming RabbitMQ messages once the host, and with it the tracer provider, has started
 var rabbitMQService = app.Services.GetRequiredService<IRabbitMQService>(); // Get the RabbitMQ service
-rabbitMQService.StartConsuming(message =>
+app.Lifetime.ApplicationStarted.Register(() => rabbitMQService.StartConsuming(async (message, finalAttempt) =>
 {
-    try
-    {
-        using var scope = app.Services.CreateScope(); // Create a scope for dependency injection
-        var logger = scope.ServiceProvider.GetRequiredService<ILogger<RabbitMQService>>(); // Get the logger instance
-        logger.LogInformation("Consuming message and creating scope."); // Use the logger instance
-        var reportService = scope.ServiceProvider.GetRequiredService<IReportGenerationService>(); // Get the report generation service
-        logger.LogInformation("Report service resolved, calling GenerateReport."); // Use the logger instance
-        reportService.GenerateReport(message); // Generate a report based on the received message
-        logger.LogInformation("GenerateReport called."); // Use the logger instance
-    }
-    catch (Exception ex)
-    {
-        Console.WriteLine("An error occurred while consuming the message: " + ex.Message);
-    }
-
-});
+    using var scope = app.Services.CreateScope(); // Create a scope for dependency injection
+    var logger = scope.ServiceProvider.GetRequiredService<ILogger<RabbitMQService>>(); // Get the logger instance
+    logger.LogInformation("Consuming message and creating scope."); // Use the logger instance
+    var reportService = scope.ServiceProvider.GetRequiredService<IReportGenerationService>(); // Get the report generation service
+    logger.LogInformation("Report service resolved, calling GenerateReport."); // Use the logger instance
+    // Errors propagate so the message is rejected and delivered again, or parked after the final attempt
+    await reportService.GenerateReport(message, finalAttempt); // Generate a report based on the received message
+    logger.LogInformation("GenerateReport completed."); // Use the logger instance
+}));
 
 app.Run(); // Run the application
@@ -1,6 +1,8 @@
 using RabbitMQ.Client;
 using RabbitMQ.Client.Events;
+using System.Diagnostics;
 using System.Text;
+using System.Text.Json;
 
 namespace ReportService.Services
 {
@@ -9,27 +11,126 @@ namespace ReportService.Services
         private readonly IConnection _connection; // Connection to the RabbitMQ server
         private readonly IModel _channel; // Channel for communication with RabbitMQ
         private readonly string _queueName; // Name of the queue to consume messages from
+        private readonly object _publishLock = new object(); // Channels must not be used from several threads at once
+
+        private readonly int _maxDeliveryAttempts; // Deliveries of a request before it is parked in the dead-letter queue
+
+        // Queue hotel-service consumes report status updates from; it is declared by hotel-service
+        private const string StatusQueueName = "report_status";
+
+        // Exchange of hotel-service's topology (internal/messaging/topology.go) whose queue parks requests that keep failing
+        private const string DeadLetterExchangeName = "hotel.reports.dead";
+
+        // Deliveries of a request before it is parked, as ReportMaxDeliveryTries in hotel-service's topology
+        private const int DefaultMaxDeliveryAttempts = 5;
+
+        // Name of the source of the spans of processed report requests, registered with the tracer provider in Program.cs
+        public const string ActivitySourceName = "ReportService.RabbitMQ";
+
+        private static readonly ActivitySource ActivitySource = new ActivitySource(ActivitySourceName);
 
         // Constructor that initializes the RabbitMQ connection and declares the queue
         public RabbitMQService(IConfiguration configuration, IConnectionFactory connectionFactory)
         {
             _queueName = configuration["RabbitMQ:QueueName"] ?? throw new ArgumentNullException(nameof(configuration)); // Retrieve queue name from config
+            _maxDeliveryAttempts = int.TryParse(configuration["RabbitMQ:MaxDeliveryAttempts"], out var attempts) && attempts > 0
+                ? attempts
+                : DefaultMaxDeliveryAttempts;
             _connection = connectionFactory.CreateConnection(); // Establish RabbitMQ connection
             _channel = _connection.CreateModel(); // Create RabbitMQ channel
-            _channel.QueueDeclare(queue: _queueName, durable: false, exclusive: false, autoDelete: false, arguments: null); // Declare queue
+            // Declare the queue exactly as hotel-service's topology does (internal/messaging/topology.go): durable, with
+            // rejected requests dead-lettered to the retry exchange. Declaring it with other arguments fails.
+            var arguments = new Dictionary<string, object> { ["x-dead-letter-exchange"] = "hotel.reports.retry" };
+            _channel.QueueDeclare(queue: _queueName, durable: true, exclusive: false, autoDelete: false, arguments: arguments); // Declare queue
         }
 
-        // Method to start consuming messages from the queue
-        public void StartConsuming(Action<string> processMessage)
+        // Method to start consuming messages from the queue. A message is acknowledged once processMessage completes.
+        // If it throws, the message is rejected and hotel-service's topology delivers it again after a delay, until
+        // the final attempt, after which it is published to the dead-letter exchange. processMessage is told whether
+        // the current delivery is the final attempt. The connection factory must dispatch consumers asynchronously.
+        public void StartConsuming(Func<string, bool, Task> processMessage)
         {
-            var consumer = new EventingBasicConsumer(_channel); // Create a consumer
-            consumer.Received += (model, ea) =>
+            lock (_publishLock)
+            {
+                _channel.BasicQos(prefetchSize: 0, prefetchCount: 1, global: false); // Process one request at a time
+            }
+            var consumer = new AsyncEventingBasicConsumer(_channel); // Create a consumer
+            consumer.Received += async (model, ea) =>
             {
                 var body = ea.Body.ToArray(); // Get the message body
                 var message = Encoding.UTF8.GetString(body); // Convert the message body to a string
-                processMessage(message); // Process the received message
+                // Continue the trace of the request that queued the message, so the GraphQL queries sent while
+                // processing it carry its traceparent header back to hotel-service
+                ActivityContext.TryParse(ReadHeader(ea.BasicProperties, "traceparent"), ReadHeader(ea.BasicProperties, "tracestate"), isRemote: true, out var parentContext);
+                using var activity = ActivitySource.StartActivity($"{_queueName} process", ActivityKind.Consumer, parentContext);
+                activity?.SetTag("messaging.system", "rabbitmq");
+                activity?.SetTag("messaging.source.name", _queueName);
+                activity?.SetTag("messaging.operation", "process");
+                var attempt = PreviousAttempts(ea.BasicProperties) + 1;
+                try
+                {
+                    await processMessage(message, attempt >= _maxDeliveryAttempts); // Process the received message
+                    lock (_publishLock)
+                    {
+                        _channel.BasicAck(ea.DeliveryTag, multiple: false);
+                    }
+                }
+                catch (Exception ex)
+                {
+                    activity?.SetStatus(ActivityStatusCode.Error, ex.Message);
+                    Console.WriteLine($"Processing attempt {attempt} of {_maxDeliveryAttempts} failed: {ex.Message}");
+                    lock (_publishLock)
+                    {
+                        if (attempt >= _maxDeliveryAttempts)
+                        {
+                            // Park the request for manual inspection; the x-death header records its history
+                            _channel.BasicPublish(exchange: DeadLetterExchangeName, routingKey: ea.RoutingKey, basicProperties: ea.BasicProperties, body: ea.Body);
+                            _channel.BasicAck(ea.DeliveryTag, multiple: false);
+                        }
+                        else
+                        {
+                            _channel.BasicNack(ea.DeliveryTag, multiple: false, requeue: false); // Dead-lettered to the retry queue
+                        }
+                    }
+                }
             };
-            _channel.BasicConsume(queue: _queueName, autoAck: true, consumer: consumer); // Start consuming messages
+            _channel.BasicConsume(queue: _queueName, autoAck: false, consumer: consumer); // Start consuming messages
+        }
+
+        // Returns how often a message was rejected from the queue before, as counted by the broker in the x-death
+        // header each time it dead-letters the message to the retry exchange
+        private int PreviousAttempts(IBasicProperties? properties)
+        {
+            if (properties?.Headers == null || !properties.Headers.TryGetValue("x-death", out var value) || value is not IEnumerable<object> deaths)
+            {
+                return 0;
+            }
+            foreach (var death in deaths.OfType<IDictionary<string, object>>())
+            {
+                if (death.TryGetValue("queue", out var queue) && HeaderString(queue) == _queueName
+                    && death.TryGetValue("reason", out var reason) && HeaderString(reason) == "rejected"
+                    && death.TryGetValue("count", out var count))
+                {
+                    return Convert.ToInt32(count);
+                }
+            }
+            return 0;
+        }
+
+        // Returns a string header of a message, e.g. the W3C trace context hotel-service adds to report requests
+        private static string? ReadHeader(IBasicProperties? properties, string name)
+        {
+            if (properties?.Headers == null || !properties.Headers.TryGetValue(name, out var value))
+            {
+                return null;
+            }
+            return HeaderString(value);
+        }
+
+        // Converts a header value to a string; AMQP delivers strings as bytes
+        private static string? HeaderString(object? value)
+        {
+            return value is byte[] bytes ? Encoding.UTF8.GetString(bytes) : value?.ToString();
         }
 
         // Dispose method to clean up resources
@@ -43,7 +144,24 @@ namespace ReportService.Services
         public void PublishMessage(string message)
         {
             var body = Encoding.UTF8.GetBytes(message); // Convert the message to a byte array
-            _channel.BasicPublish(exchange: "", routingKey: _queueName, basicProperties: null, body: body); // Publish the message to the queue
+            lock (_publishLock)
+            {
+                _channel.BasicPublish(exchange: "", routingKey: _queueName, basicProperties: null, body: body); // Publish the message to the queue
+            }
+        }
+
+        // Method to publish a report status update (processing, completed or failed) for hotel-service
+        public void PublishStatus(Guid reportRequestId, string status)
+        {
+            var message = JsonSerializer.Serialize(new { id = reportRequestId, status }); // Same shape as hotel-service's ReportStatusUpdate
+            var body = Encoding.UTF8.GetBytes(message);
+            lock (_publishLock)
+            {
+                var properties = _channel.CreateBasicProperties();
+                properties.Persistent = true; // The status queue is durable
+                properties.ContentType = "application/json";
+                _channel.BasicPublish(exchange: "", routingKey: StatusQueueName, basicProperties: properties, body: body);
+            }
         }
     }
 }
@@ -14,26 +14,31 @@ namespace ReportService.Services
         private readonly IReportRepository _reportRepository; // Repository for report data
         private readonly ILogger<ReportGenerationService> _logger; // Logger for logging information and errors
         private readonly IGraphQLClient _graphQLClient; // Client for sending GraphQL queries
+        private readonly IRabbitMQService? _rabbitMQService; // Publishes status updates back to hotel-service
 
         // Constructor to initialize dependencies
         public ReportGenerationService(
             IReportRepository reportRepository,
             ILogger<ReportGenerationService> logger,
-            IGraphQLClient graphQLClient)
+            IGraphQLClient graphQLClient,
+            IRabbitMQService? rabbitMQService = null)
         {
             _reportRepository = reportRepository; // Assigning the report repository
             _logger = logger; // Assigning the logger
             _graphQLClient = graphQLClient; // Assigning the GraphQL client
+            _rabbitMQService = rabbitMQService; // Assigning the status publisher
         }
 
         // Method to generate a report based on the incoming message
         // Parameters:
         // - message: JSON string containing report request details
-        public async Task GenerateReport(string message)
+        // - finalAttempt: whether the message will not be delivered again if this attempt fails
+        public async Task GenerateReport(string message, bool finalAttempt = true)
         {
             Console.WriteLine("Starting report generation process");
             _logger.LogInformation($"Received message: {message}");
 
+            var parsed = false; // Failures after parsing may pass when the message is delivered again
             try
             {
                 var options = new JsonSerializerOptions
@@ -41,24 +46,31 @@ namespace ReportService.Services
                     PropertyNameCaseInsensitive = true
                 };
 
-                var reportRequest = JsonSerializer.Deserialize<ReportRequest>(message, options);
+                var reportRequest = ParseReportRequest(message, options);
+                parsed = true;
                 _logger.LogInformation($"Deserialized report request: {JsonSerializer.Serialize(reportRequest)}");
 
                 if (reportRequest != null)
                 {
-                    var report = new Report
+                    // A redelivered request continues with the report created by the earlier attempt
+                    var report = await _reportRepository.GetByReportRequestIdAsync(reportRequest.Id);
+                    if (report == null)
                     {
-                        ReportRequestId = reportRequest.Id,
-                        RequestDate = DateTime.UtcNow,
-                        Status = "Processing",
-                        Location = reportRequest.Location ?? "Unknown",
-                        HotelCount = 0,
-                        PhoneNumberCount = 0
-                    };
-
-                    _logger.LogInformation($"Created initial report: {JsonSerializer.Serialize(report)}");
-
-                    await _reportRepository.AddAsync(report);
+                        report = new Report
+                        {
+                            ReportRequestId = reportRequest.Id,
+                            RequestDate = DateTime.UtcNow,
+                            Status = "Processing",
+                            Location = reportRequest.Location ?? "Unknown",
+                            HotelCount = 0,
+                            PhoneNumberCount = 0
+                        };
+
+                        _logger.LogInformation($"Created initial report: {JsonSerializer.Serialize(report)}");
+
+                        await _reportRepository.AddAsync(report);
+                        PublishStatus(reportRequest.Id, "processing");
+                    }
 
                     try
                     {
@@ -82,14 +94,21 @@ namespace ReportService.Services
                         report.Status = "Completed";
 
                         await _reportRepository.UpdateAsync(report);
+                        PublishStatus(reportRequest.Id, "completed");
 
                         _logger.LogInformation($"Updated report: {JsonSerializer.Serialize(report)}");
                     }
+                    catch (Exception ex) when (!finalAttempt)
+                    {
+                        _logger.LogWarning(ex, "Error processing GraphQL queries, report request {ReportRequestId} will be retried", reportRequest.Id);
+                        throw;
+                    }
                     catch (Exception ex)
                     {
                         _logger.LogError(ex, "Error processing GraphQL queries");
                         report.Status = "Error";
                         await _reportRepository.UpdateAsync(report);
+                        PublishStatus(reportRequest.Id, "failed");
                     }
                 }
                 else
@@ -98,13 +117,52 @@ namespace ReportService.Services
                     await CreateErrorReport("Failed to deserialize report request", "Unknown");
                 }
             }
-            catch (Exception ex)
+            catch (Exception ex) when (!parsed && (ex is JsonException || ex is NotSupportedException))
             {
+                // Malformed or unsupported messages fail on every delivery, so they are not retried
                 _logger.LogError(ex, "Error generating report: {Message}", ex.Message);
                 await CreateErrorReport($"Error generating report: {ex.Message}", "Unknown");
             }
         }
 
+        // Event type and payload version of report requests published by hotel-service
+        private const string ReportRequestedEventType = "report.requested";
+        private const int ReportRequestedEventVersion = 1;
+
+        // Method to read a report request from a message. hotel-service wraps requests in a versioned
+        // event envelope ({type, version, id, occurred_at, trace_id, payload}); bare requests queued
+        // before the envelope was introduced are still accepted.
+        private static ReportRequest? ParseReportRequest(string message, JsonSerializerOptions options)
+        {
+            using var document = JsonDocument.Parse(message);
+            var root = document.RootElement;
+            if (root.ValueKind == JsonValueKind.Object
+                && root.TryGetProperty("type", out var type)
+                && root.TryGetProperty("payload", out var payload))
+            {
+                var version = root.TryGetProperty("version", out var v) && v.TryGetInt32(out var n) ? n : 0;
+                if (type.GetString() != ReportRequestedEventType || version != ReportRequestedEventVersion)
+                {
+                    throw new NotSupportedException($"Unsupported event {type.GetString()} version {version}");
+                }
+                return payload.Deserialize<ReportRequest>(options);
+            }
+            return root.Deserialize<ReportRequest>(options);
+        }
+
+        // Method to report a status transition to hotel-service; a failure to publish must not fail the report
+        private void PublishStatus(Guid reportRequestId, string status)
+        {
+            try
+            {
+                _rabbitMQService?.PublishStatus(reportRequestId, status);
+            }
+            catch (Exception ex)
+            {
+                _logger.LogError(ex, "Failed to publish status {Status} for report request {ReportRequestId}", status, reportRequestId);
+            }
+        }
+
         // Method to create an error report
         private async Task CreateErrorReport(string errorMessage, string location = "Unknown")
         {
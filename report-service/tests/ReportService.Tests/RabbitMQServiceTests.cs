@@ -2,9 +2,12 @@ using System;
 using Microsoft.Extensions.Configuration;
 using Moq;
 using RabbitMQ.Client;
+using RabbitMQ.Client.Events;
 using ReportService.Services;
 using Xunit;
 using System.Collections.Generic;
+using System.Diagnostics;
+using System.Threading.Tasks;
 
 namespace ReportService.Tests
 {
@@ -78,12 +81,12 @@ namespace ReportService.Tests
             var rabbitMQService = new RabbitMQService(_mockConfiguration.Object, _mockConnectionFactory.Object);
 
             // Act
-            rabbitMQService.StartConsuming(_ => { });
+            rabbitMQService.StartConsuming((_, _) => Task.CompletedTask);
 
-            // Assert that the consumer is set up correctly
+            // Assert that the consumer is set up correctly, acknowledging messages once they are processed
             _mockChannel.Verify(ch => ch.BasicConsume(
                 It.IsAny<string>(),
-                It.IsAny<bool>(),
+                false,
                 It.IsAny<string>(),
                 It.IsAny<bool>(),
                 It.IsAny<bool>(),
@@ -91,5 +94,110 @@ namespace ReportService.Tests
                 It.IsAny<IBasicConsumer>()
             ), Times.Once);
         }
+
+        // Starts consuming with the given callback and returns the registered consumer
+        private AsyncEventingBasicConsumer StartConsumer(RabbitMQService rabbitMQService, Func<string, bool, Task> processMessage)
+        {
+            IBasicConsumer consumer = null;
+            _mockChannel.Setup(ch => ch.BasicConsume(
+                It.IsAny<string>(), It.IsAny<bool>(), It.IsAny<string>(), It.IsAny<bool>(), It.IsAny<bool>(),
+                It.IsAny<IDictionary<string, object>>(), It.IsAny<IBasicConsumer>()))
+                .Callback(new InvocationAction(invocation => consumer = (IBasicConsumer)invocation.Arguments[6]))
+                .Returns("consumer-tag");
+            rabbitMQService.StartConsuming(processMessage);
+            return Assert.IsType<AsyncEventingBasicConsumer>(consumer);
+        }
+
+        // Returns message properties whose x-death header records the given number of rejections from test_queue
+        private static IBasicProperties RejectedProperties(long rejections)
+        {
+            var properties = new Mock<IBasicProperties>();
+            var death = new Dictionary<string, object>
+            {
+                ["queue"] = System.Text.Encoding.UTF8.GetBytes("test_queue"),
+                ["reason"] = System.Text.Encoding.UTF8.GetBytes("rejected"),
+                ["count"] = rejections
+            };
+            properties.Setup(p => p.Headers).Returns(new Dictionary<string, object> { ["x-death"] = new List<object> { death } });
+            return properties.Object;
+        }
+
+        [Fact]
+        public async Task FailedMessage_IsRejectedForRetry()
+        {
+            // Arrange
+            var rabbitMQService = new RabbitMQService(_mockConfiguration.Object, _mockConnectionFactory.Object);
+            var finalAttempts = new List<bool>();
+            var consumer = StartConsumer(rabbitMQService, (_, finalAttempt) =>
+            {
+                finalAttempts.Add(finalAttempt);
+                throw new InvalidOperationException("hotel-service unavailable");
+            });
+
+            // Act: deliver a message that failed once before
+            await consumer.HandleBasicDeliver("consumer-tag", 7, true, "hotel.reports", "test_queue", RejectedProperties(1), new byte[] { });
+
+            // Assert that the message is dead-lettered to the retry queue rather than requeued or parked
+            Assert.Equal(new[] { false }, finalAttempts);
+            _mockChannel.Verify(ch => ch.BasicNack(7, false, false), Times.Once);
+            _mockChannel.Verify(ch => ch.BasicAck(It.IsAny<ulong>(), It.IsAny<bool>()), Times.Never);
+        }
+
+        [Fact]
+        public async Task MessageFailingItsFinalAttempt_IsParkedInTheDeadLetterExchange()
+        {
+            // Arrange
+            var rabbitMQService = new RabbitMQService(_mockConfiguration.Object, _mockConnectionFactory.Object);
+            var finalAttempts = new List<bool>();
+            var consumer = StartConsumer(rabbitMQService, (_, finalAttempt) =>
+            {
+                finalAttempts.Add(finalAttempt);
+                throw new InvalidOperationException("report database unavailable");
+            });
+
+            // Act: deliver a message that failed four times before, so this is the fifth and final attempt
+            await consumer.HandleBasicDeliver("consumer-tag", 7, true, "hotel.reports", "test_queue", RejectedProperties(4), new byte[] { });
+
+            // Assert that the message is published to the dead-letter exchange and removed from the queue
+            Assert.Equal(new[] { true }, finalAttempts);
+            _mockChannel.Verify(ch => ch.BasicPublish("hotel.reports.dead", "test_queue", It.IsAny<bool>(), It.IsAny<IBasicProperties>(), It.IsAny<ReadOnlyMemory<byte>>()), Times.Once);
+            _mockChannel.Verify(ch => ch.BasicAck(7, false), Times.Once);
+            _mockChannel.Verify(ch => ch.BasicNack(It.IsAny<ulong>(), It.IsAny<bool>(), It.IsAny<bool>()), Times.Never);
+        }
+
+        [Fact]
+        public async Task Message_IsProcessedInAConsumerSpanContinuingItsTrace()
+        {
+            // Arrange: record the spans of the service as the tracer provider does
+            using var listener = new ActivityListener
+            {
+                ShouldListenTo = source => source.Name == RabbitMQService.ActivitySourceName,
+                Sample = (ref ActivityCreationOptions<ActivityContext> _) => ActivitySamplingResult.AllDataAndRecorded
+            };
+            ActivitySource.AddActivityListener(listener);
+            var rabbitMQService = new RabbitMQService(_mockConfiguration.Object, _mockConnectionFactory.Object);
+            Activity processing = null;
+            var consumer = StartConsumer(rabbitMQService, (_, _) =>
+            {
+                processing = Activity.Current;
+                return Task.CompletedTask;
+            });
+            var properties = new Mock<IBasicProperties>();
+            properties.Setup(p => p.Headers).Returns(new Dictionary<string, object>
+            {
+                ["traceparent"] = System.Text.Encoding.UTF8.GetBytes("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+            });
+
+            // Act
+            await consumer.HandleBasicDeliver("consumer-tag", 7, true, "hotel.reports", "test_queue", properties.Object, new byte[] { });
+
+            // Assert that the message was processed within a consumer span of the trace that queued it
+            Assert.NotNull(processing);
+            Assert.Equal(RabbitMQService.ActivitySourceName, processing.Source.Name);
+            Assert.Equal(ActivityKind.Consumer, processing.Kind);
+            Assert.Equal("test_queue process", processing.DisplayName);
+            Assert.Equal("4bf92f3577b34da6a3ce929d0e0e4736", processing.TraceId.ToHexString());
+            Assert.Equal("00f067aa0ba902b7", processing.ParentSpanId.ToHexString());
+        }
     }
 }